@@ -0,0 +1,417 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golox/lox"
+)
+
+/*
+Debug Adapter Protocol server over stdio, for debugging lox programs in editors
+like VS Code and Neovim - https://microsoft.github.io/debug-adapter-protocol/
+
+Requests are handled on one goroutine while the program runs on another. When
+the program pauses, it waits in Paused till a continue or step request comes,
+and the requests in between inspect the paused program. There is a single
+thread with the id 1, and frame ids are indexes in the call stack, 0 being the
+innermost.
+*/
+type dapServer struct {
+	reader *bufio.Reader
+
+	writeMu sync.Mutex
+	writer  io.Writer
+	seq     int
+
+	debugger    *lox.Debugger
+	program     string
+	code        []byte
+	stopOnEntry bool
+	ctx         context.Context // cancelled to stop the program
+	cancel      context.CancelFunc
+	done        chan struct{} // closed when the program finishes
+
+	mu        sync.Mutex
+	session   *lox.DebugSession // nil while the program isn't paused
+	scopes    map[int]lox.Scope // variablesReference to its scope, while paused
+	resume    chan lox.DebugAction
+	isStarted bool
+}
+
+type dapRequest struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type dapResponse struct {
+	Seq        int    `json:"seq"`
+	Type       string `json:"type"`
+	RequestSeq int    `json:"request_seq"`
+	Success    bool   `json:"success"`
+	Command    string `json:"command"`
+	Message    string `json:"message,omitempty"`
+	Body       any    `json:"body,omitempty"`
+}
+
+type dapEvent struct {
+	Seq   int    `json:"seq"`
+	Type  string `json:"type"`
+	Event string `json:"event"`
+	Body  any    `json:"body,omitempty"`
+}
+
+const dapThreadId = 1
+
+// serves requests till the client disconnects or closes the input
+func serveDAP(in io.Reader, out io.Writer) error {
+	s := &dapServer{
+		reader: bufio.NewReader(in),
+		writer: out,
+		scopes: make(map[int]lox.Scope),
+		resume: make(chan lox.DebugAction),
+		done:   make(chan struct{}),
+	}
+	s.debugger = lox.NewDebugger(s)
+	for {
+		req, err := s.read()
+		if err == io.EOF {
+			s.stop()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if isDisconnect := s.handle(req); isDisconnect {
+			return nil
+		}
+	}
+}
+
+func (s *dapServer) read() (dapRequest, error) {
+	var req dapRequest
+	content, err := readMessage(s.reader)
+	if err != nil {
+		return req, err
+	}
+	err = json.Unmarshal(content, &req)
+	return req, err
+}
+
+func (s *dapServer) write(message any) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.seq++
+	switch message := message.(type) {
+	case *dapResponse:
+		message.Seq = s.seq
+	case *dapEvent:
+		message.Seq = s.seq
+	}
+	content, _ := json.Marshal(message)
+	writeMessage(s.writer, content)
+}
+
+func (s *dapServer) respond(req dapRequest, body any) {
+	s.write(&dapResponse{Type: "response", RequestSeq: req.Seq, Success: true, Command: req.Command, Body: body})
+}
+
+func (s *dapServer) respondError(req dapRequest, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	s.write(&dapResponse{Type: "response", RequestSeq: req.Seq, Command: req.Command, Message: message})
+}
+
+func (s *dapServer) sendEvent(event string, body any) {
+	s.write(&dapEvent{Type: "event", Event: event, Body: body})
+}
+
+// gives whether the session has ended
+func (s *dapServer) handle(req dapRequest) bool {
+	switch req.Command {
+	case "initialize":
+		s.respond(req, map[string]any{
+			"supportsConfigurationDoneRequest": true,
+			"supportsEvaluateForHovers":        true,
+			"supportsTerminateRequest":         true,
+		})
+		s.sendEvent("initialized", nil)
+	case "launch":
+		s.launch(req)
+	case "setBreakpoints":
+		s.setBreakpoints(req)
+	case "configurationDone":
+		s.respond(req, nil)
+		s.start()
+	case "threads":
+		s.respond(req, map[string]any{
+			"threads": []map[string]any{{"id": dapThreadId, "name": "main"}},
+		})
+	case "stackTrace":
+		s.stackTrace(req)
+	case "scopes":
+		s.scopesRequest(req)
+	case "variables":
+		s.variables(req)
+	case "evaluate":
+		s.evaluate(req)
+	case "continue":
+		s.resumeWith(req, lox.DebugContinue, map[string]any{"allThreadsContinued": true})
+	case "next":
+		s.resumeWith(req, lox.DebugStepOver, nil)
+	case "stepIn":
+		s.resumeWith(req, lox.DebugStepInto, nil)
+	case "stepOut":
+		s.resumeWith(req, lox.DebugStepOut, nil)
+	case "terminate":
+		s.stop()
+		s.respond(req, nil)
+	case "disconnect":
+		s.stop()
+		s.respond(req, nil)
+		return true
+	default:
+		s.respondError(req, "Unsupported request '%s'.", req.Command)
+	}
+	return false
+}
+
+func (s *dapServer) launch(req dapRequest) {
+	var args struct {
+		Program     string   `json:"program"`
+		StopOnEntry bool     `json:"stopOnEntry"`
+		Args        []string `json:"args"`
+	}
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		s.respondError(req, "Invalid launch arguments: %v", err)
+		return
+	}
+	code, err := os.ReadFile(args.Program)
+	if err != nil {
+		s.respondError(req, "Error reading file: %v", err)
+		return
+	}
+	s.program, s.code, s.stopOnEntry = args.Program, code, args.StopOnEntry
+
+	// stdin and stdout are used for the protocol, so the program's output is sent as
+	// events and it has no input
+	output := func(category string, text string) {
+		s.sendEvent("output", map[string]any{"category": category, "output": text + "\n"})
+	}
+	noInput := func() (string, error) { return "", io.EOF }
+	lox.SetLogger(lox.Logger{
+		Input:    func(prompt string) (string, error) { return noInput() },
+		ReadLine: noInput,
+		ReadAll:  noInput,
+		Print: func(str string) {
+			output("stdout", str)
+		},
+		ScanError: func(line int, col int, msg string) {
+			output("stderr", fmt.Sprintf("[line %d:%d] %s", line, col, msg))
+		},
+		ParseError: func(token lox.TokenLogMeta, msg string) {
+			output("stderr", fmt.Sprintf("[line %d:%d] %s", token.Line, token.Col, msg))
+		},
+		RuntimeError: func(token lox.TokenLogMeta, msg string) {
+			output("stderr", fmt.Sprintf("[line %d:%d] %s", token.Line, token.Col, msg))
+		},
+	})
+	lox.ResetErrorState()
+	lox.SetScriptArgs(args.Args)
+	s.respond(req, nil)
+}
+
+func (s *dapServer) setBreakpoints(req dapRequest) {
+	var args struct {
+		Breakpoints []struct {
+			Line int `json:"line"`
+		} `json:"breakpoints"`
+	}
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		s.respondError(req, "Invalid setBreakpoints arguments: %v", err)
+		return
+	}
+	lines := make([]int, len(args.Breakpoints))
+	breakpoints := make([]map[string]any, len(args.Breakpoints))
+	for idx, bp := range args.Breakpoints {
+		lines[idx] = bp.Line
+		breakpoints[idx] = map[string]any{"verified": true, "line": bp.Line}
+	}
+	s.debugger.SetBreakpoints(lines)
+	s.respond(req, map[string]any{"breakpoints": breakpoints})
+}
+
+// runs the launched program, after the client is done setting the breakpoints
+func (s *dapServer) start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.isStarted || s.code == nil {
+		return
+	}
+	s.isStarted = true
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	go func() {
+		defer close(s.done)
+		exitCode := s.debugger.Run(s.code, s.ctx, s.stopOnEntry)
+		s.sendEvent("exited", map[string]any{"exitCode": exitCode})
+		s.sendEvent("terminated", nil)
+	}()
+}
+
+// stops the program if it's running and waits for it to finish
+func (s *dapServer) stop() {
+	s.mu.Lock()
+	if !s.isStarted {
+		s.mu.Unlock()
+		return
+	}
+	s.cancel()
+	s.session = nil
+	s.mu.Unlock()
+	<-s.done
+}
+
+// called on the program's goroutine when it pauses
+func (s *dapServer) Paused(session *lox.DebugSession, reason string) lox.DebugAction {
+	s.mu.Lock()
+	s.session = session
+	clear(s.scopes)
+	s.mu.Unlock()
+	s.sendEvent("stopped", map[string]any{
+		"reason":            reason,
+		"threadId":          dapThreadId,
+		"allThreadsStopped": true,
+	})
+	select {
+	case action := <-s.resume:
+		return action
+	case <-s.ctx.Done():
+		return lox.DebugTerminate
+	}
+}
+
+// the session is cleared before resuming, so no request uses it while the program runs
+func (s *dapServer) resumeWith(req dapRequest, action lox.DebugAction, body any) {
+	s.mu.Lock()
+	isPaused := s.session != nil
+	s.session = nil
+	s.mu.Unlock()
+	if !isPaused {
+		s.respondError(req, "The program isn't paused.")
+		return
+	}
+	s.respond(req, body)
+	select {
+	case s.resume <- action:
+	case <-s.done:
+	}
+}
+
+func (s *dapServer) pausedSession(req dapRequest) *lox.DebugSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.session == nil {
+		s.respondError(req, "The program isn't paused.")
+	}
+	return s.session
+}
+
+func (s *dapServer) stackTrace(req dapRequest) {
+	session := s.pausedSession(req)
+	if session == nil {
+		return
+	}
+	source := map[string]any{"name": filepath.Base(s.program), "path": s.program}
+	var frames []map[string]any
+	for idx, frame := range session.CallStack() {
+		frames = append(frames, map[string]any{
+			"id":     idx,
+			"name":   frame.Name,
+			"line":   frame.Line,
+			"column": frame.Column,
+			"source": source,
+		})
+	}
+	s.respond(req, map[string]any{"stackFrames": frames, "totalFrames": len(frames)})
+}
+
+func (s *dapServer) scopesRequest(req dapRequest) {
+	var args struct {
+		FrameId int `json:"frameId"`
+	}
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		s.respondError(req, "Invalid scopes arguments: %v", err)
+		return
+	}
+	session := s.pausedSession(req)
+	if session == nil {
+		return
+	}
+	var scopes []map[string]any
+	s.mu.Lock()
+	for _, scope := range session.Scopes(args.FrameId) {
+		ref := len(s.scopes) + 1
+		s.scopes[ref] = scope
+		scopes = append(scopes, map[string]any{
+			"name":               scope.Name,
+			"variablesReference": ref,
+			"expensive":          scope.Name == "globals",
+		})
+	}
+	s.mu.Unlock()
+	s.respond(req, map[string]any{"scopes": scopes})
+}
+
+func (s *dapServer) variables(req dapRequest) {
+	var args struct {
+		VariablesReference int `json:"variablesReference"`
+	}
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		s.respondError(req, "Invalid variables arguments: %v", err)
+		return
+	}
+	s.mu.Lock()
+	scope, ok := s.scopes[args.VariablesReference]
+	s.mu.Unlock()
+	if !ok {
+		s.respondError(req, "Unknown variablesReference %d.", args.VariablesReference)
+		return
+	}
+	variables := []map[string]any{}
+	for _, variable := range scope.Variables {
+		variables = append(variables, map[string]any{
+			"name":               variable.Name,
+			"value":              variable.Value,
+			"variablesReference": 0,
+		})
+	}
+	s.respond(req, map[string]any{"variables": variables})
+}
+
+func (s *dapServer) evaluate(req dapRequest) {
+	var args struct {
+		Expression string `json:"expression"`
+		FrameId    int    `json:"frameId"`
+	}
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		s.respondError(req, "Invalid evaluate arguments: %v", err)
+		return
+	}
+	session := s.pausedSession(req)
+	if session == nil {
+		return
+	}
+	result, err := session.Evaluate(args.Expression, args.FrameId)
+	if err != nil {
+		s.respondError(req, "%s", err.Error())
+		return
+	}
+	s.respond(req, map[string]any{"result": result, "variablesReference": 0})
+}
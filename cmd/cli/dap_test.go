@@ -0,0 +1,254 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const dapTestProgram = `fun add(a, b) {
+  var sum = a + b;
+  return sum;
+}
+
+var x = 10;
+print add(x, 5);
+print "done";
+`
+
+type dapClient struct {
+	protocolClient
+	seq    int
+	events []map[string]any // received while waiting for something else
+}
+
+func newDAPClient(t *testing.T) *dapClient {
+	return &dapClient{protocolClient: newProtocolClient(t, serveDAP)}
+}
+
+func writeProgram(t *testing.T, code string) string {
+	path := filepath.Join(t.TempDir(), "program.lox")
+	if err := os.WriteFile(path, []byte(code), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func (c *dapClient) send(command string, arguments any) {
+	c.t.Helper()
+	c.seq++
+	c.sendMessage(map[string]any{
+		"seq": c.seq, "type": "request", "command": command, "arguments": arguments,
+	})
+}
+
+// sends the request and gives the response, keeping the events which come before it
+func (c *dapClient) request(command string, arguments any) map[string]any {
+	c.t.Helper()
+	c.send(command, arguments)
+	for {
+		message := c.receive()
+		if message["type"] == "event" {
+			c.events = append(c.events, message)
+			continue
+		}
+		if message["command"] != command || message["request_seq"] != float64(c.seq) {
+			c.t.Fatalf("expected the response to %s, got %v", command, message)
+		}
+		return message
+	}
+}
+
+func (c *dapClient) successBody(command string, arguments any) map[string]any {
+	c.t.Helper()
+	response := c.request(command, arguments)
+	if response["success"] != true {
+		c.t.Fatalf("%s failed: %v", command, response["message"])
+	}
+	body, _ := response["body"].(map[string]any)
+	return body
+}
+
+func (c *dapClient) waitForEvent(event string) map[string]any {
+	c.t.Helper()
+	for idx, message := range c.events {
+		if message["event"] == event {
+			c.events = append(c.events[:idx], c.events[idx+1:]...)
+			return message
+		}
+	}
+	for {
+		message := c.receive()
+		if message["event"] == event {
+			return message
+		}
+		c.events = append(c.events, message)
+	}
+}
+
+// the output sent by the program so far
+func (c *dapClient) output() string {
+	var sb strings.Builder
+	for _, message := range c.events {
+		if message["event"] == "output" {
+			sb.WriteString(message["body"].(map[string]any)["output"].(string))
+		}
+	}
+	return sb.String()
+}
+
+func (c *dapClient) expectStopped(reason string, line int) {
+	c.t.Helper()
+	stopped := c.waitForEvent("stopped")
+	if got := stopped["body"].(map[string]any)["reason"]; got != reason {
+		c.t.Fatalf("expected to stop for %s, stopped for %v", reason, got)
+	}
+	frames := c.successBody("stackTrace", map[string]any{"threadId": 1})["stackFrames"].([]any)
+	if got := frames[0].(map[string]any)["line"]; got != float64(line) {
+		c.t.Fatalf("expected to stop at line %d, stopped at %v", line, got)
+	}
+}
+
+func (c *dapClient) launch(program string, stopOnEntry bool, breakpoints ...int) {
+	c.t.Helper()
+	c.successBody("initialize", map[string]any{"adapterID": "golox"})
+	c.waitForEvent("initialized")
+	c.successBody("launch", map[string]any{"program": program, "stopOnEntry": stopOnEntry})
+	var bps []map[string]any
+	for _, line := range breakpoints {
+		bps = append(bps, map[string]any{"line": line})
+	}
+	body := c.successBody("setBreakpoints", map[string]any{
+		"source": map[string]any{"path": program}, "breakpoints": bps,
+	})
+	if got := len(body["breakpoints"].([]any)); got != len(breakpoints) {
+		c.t.Fatalf("expected %d breakpoints, got %d", len(breakpoints), got)
+	}
+	c.successBody("configurationDone", nil)
+}
+
+func (c *dapClient) disconnect() {
+	c.t.Helper()
+	c.successBody("disconnect", nil)
+	c.waitServed()
+}
+
+func TestDAPBreakpointInspectAndContinue(t *testing.T) {
+	program := writeProgram(t, dapTestProgram)
+	c := newDAPClient(t)
+	c.launch(program, false, 2)
+
+	c.expectStopped("breakpoint", 2)
+	threads := c.successBody("threads", nil)["threads"].([]any)
+	if len(threads) != 1 {
+		t.Fatalf("expected a single thread, got %v", threads)
+	}
+
+	frames := c.successBody("stackTrace", map[string]any{"threadId": 1})["stackFrames"].([]any)
+	top, caller := frames[0].(map[string]any), frames[1].(map[string]any)
+	if top["name"] != "add" || top["column"] != float64(3) {
+		t.Errorf("unexpected top frame %v", top)
+	}
+	if caller["name"] != "<script>" || caller["line"] != float64(7) {
+		t.Errorf("unexpected caller frame %v", caller)
+	}
+	if path := top["source"].(map[string]any)["path"]; path != program {
+		t.Errorf("expected the source path %s, got %v", program, path)
+	}
+
+	// the parameters are in the scope enclosing the function body
+	scopes := c.successBody("scopes", map[string]any{"frameId": 0})["scopes"].([]any)
+	values := map[string]any{}
+	for _, scope := range scopes {
+		ref := scope.(map[string]any)["variablesReference"]
+		variables := c.successBody("variables", map[string]any{"variablesReference": ref})["variables"].([]any)
+		for _, variable := range variables {
+			variable := variable.(map[string]any)
+			values[variable["name"].(string)] = variable["value"]
+		}
+	}
+	if values["a"] != "10" || values["b"] != "5" || values["x"] != "10" {
+		t.Errorf("unexpected variables %v", values)
+	}
+
+	result := c.successBody("evaluate", map[string]any{"expression": "a * b", "frameId": 0})["result"]
+	if result != "50" {
+		t.Errorf("expected a * b to be 50, got %v", result)
+	}
+	response := c.request("evaluate", map[string]any{"expression": "nope", "frameId": 0})
+	if response["success"] != false || response["message"] != "Undefined variable 'nope'." {
+		t.Errorf("expected an error for an undefined variable, got %v", response)
+	}
+
+	c.successBody("next", map[string]any{"threadId": 1})
+	c.expectStopped("step", 3)
+	result = c.successBody("evaluate", map[string]any{"expression": "sum", "frameId": 0})["result"]
+	if result != "15" {
+		t.Errorf("expected sum to be 15, got %v", result)
+	}
+
+	c.successBody("continue", map[string]any{"threadId": 1})
+	exited := c.waitForEvent("exited")
+	if code := exited["body"].(map[string]any)["exitCode"]; code != float64(0) {
+		t.Errorf("expected exit code 0, got %v", code)
+	}
+	c.waitForEvent("terminated")
+	if output := c.output(); output != "15\ndone\n" {
+		t.Errorf("unexpected output %q", output)
+	}
+	c.disconnect()
+}
+
+func TestDAPSteppingFromEntry(t *testing.T) {
+	program := writeProgram(t, dapTestProgram)
+	c := newDAPClient(t)
+	c.launch(program, true)
+
+	c.expectStopped("entry", 1)
+	c.successBody("next", map[string]any{"threadId": 1})
+	c.expectStopped("step", 6)
+	c.successBody("next", map[string]any{"threadId": 1})
+	c.expectStopped("step", 7)
+	c.successBody("stepIn", map[string]any{"threadId": 1})
+	c.expectStopped("step", 2)
+	c.successBody("stepOut", map[string]any{"threadId": 1})
+	c.expectStopped("step", 8)
+	if output := c.output(); output != "15\n" {
+		t.Errorf("unexpected output %q", output)
+	}
+
+	// evaluating in an outer frame, and when not paused
+	c.successBody("continue", map[string]any{"threadId": 1})
+	c.waitForEvent("terminated")
+	response := c.request("evaluate", map[string]any{"expression": "x"})
+	if response["success"] != false {
+		t.Errorf("expected evaluate to fail after the program ended, got %v", response)
+	}
+	c.disconnect()
+}
+
+func TestDAPDisconnectWhilePaused(t *testing.T) {
+	program := writeProgram(t, "var i = 0;\nwhile (true) {\n  i = i + 1;\n}\n")
+	c := newDAPClient(t)
+	c.launch(program, false, 3)
+
+	c.expectStopped("breakpoint", 3)
+	c.successBody("continue", map[string]any{"threadId": 1})
+	c.expectStopped("breakpoint", 3)
+	result := c.successBody("evaluate", map[string]any{"expression": "i", "frameId": 0})["result"]
+	if result != "1" {
+		t.Errorf("expected i to be 1 on the second iteration, got %v", result)
+	}
+	c.disconnect()
+}
+
+func TestDAPTerminateWhileRunning(t *testing.T) {
+	program := writeProgram(t, "fun spin(n) {\n  return spin(n);\n}\nspin(1);\n")
+	c := newDAPClient(t)
+	c.launch(program, false)
+
+	c.successBody("terminate", nil)
+	c.waitForEvent("terminated")
+	c.disconnect()
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golox/lox"
+)
+
+const debugHelp = `Commands:
+  break <line>, b    add a breakpoint
+  delete <line>      remove a breakpoint
+  breakpoints        list the breakpoints
+  continue, c        run till the next breakpoint
+  next, n            step over to the next line
+  step, s            step into function calls
+  out, o             step out of the current function
+  stack, bt          print the call stack
+  vars, v            print the variables in scope
+  print <expr>, p    evaluate an expression in the current scope
+  watch <expr>       evaluate the expression every time the program pauses
+  unwatch <n>        remove the nth watch
+  list, l            show the code around the current line
+  quit, q            stop the program
+An empty line repeats the last command.`
+
+/*
+interactive debugger on the terminal, the commands are read from the same input
+as the program's input().
+*/
+type cliDebugger struct {
+	debugger    *lox.Debugger
+	sourceLines []string
+	breakpoints []int
+	watches     []string
+	lastCommand string
+}
+
+func debugFile(code []byte, ctx context.Context) int {
+	c := &cliDebugger{sourceLines: strings.Split(string(code), "\n")}
+	c.debugger = lox.NewDebugger(c)
+	fmt.Println("Debugging, type help for the commands.")
+	return c.debugger.Run(code, ctx, true)
+}
+
+func (c *cliDebugger) Paused(session *lox.DebugSession, reason string) lox.DebugAction {
+	stack := session.CallStack()
+	fmt.Printf("Paused at line %d in %s (%s)\n", session.Line(), stack[0].Name, reason)
+	c.printSource(session.Line(), 0)
+	for idx, watch := range c.watches {
+		fmt.Printf("watch %d: %s = %s\n", idx+1, watch, evaluateForDisplay(session, watch))
+	}
+
+	for {
+		fmt.Print("(debug) ")
+		input, err := readLine(stdin)
+		if err != nil { // no more commands, let the program finish
+			fmt.Println()
+			return lox.DebugContinue
+		}
+		input = strings.TrimSpace(input)
+		if input == "" {
+			input = c.lastCommand
+		}
+		c.lastCommand = input
+		command, arg, _ := strings.Cut(input, " ")
+		arg = strings.TrimSpace(arg)
+
+		switch command {
+		case "":
+		case "help", "h":
+			fmt.Println(debugHelp)
+		case "break", "b":
+			c.changeBreakpoint(arg, true)
+		case "delete":
+			c.changeBreakpoint(arg, false)
+		case "breakpoints":
+			fmt.Println("breakpoints:", c.breakpoints)
+		case "continue", "c":
+			return lox.DebugContinue
+		case "next", "n":
+			return lox.DebugStepOver
+		case "step", "s":
+			return lox.DebugStepInto
+		case "out", "o":
+			return lox.DebugStepOut
+		case "quit", "q":
+			return lox.DebugTerminate
+		case "stack", "bt":
+			for _, frame := range stack {
+				fmt.Printf("  %s at line %d\n", frame.Name, frame.Line)
+			}
+		case "vars", "v":
+			for _, scope := range session.Scopes(0) {
+				fmt.Printf("%s:\n", scope.Name)
+				for _, variable := range scope.Variables {
+					fmt.Printf("  %s = %s\n", variable.Name, variable.Value)
+				}
+			}
+		case "print", "p":
+			fmt.Println(evaluateForDisplay(session, arg))
+		case "watch":
+			c.watches = append(c.watches, arg)
+			fmt.Printf("watch %d: %s = %s\n", len(c.watches), arg, evaluateForDisplay(session, arg))
+		case "unwatch":
+			num, err := strconv.Atoi(arg)
+			if err != nil || num < 1 || num > len(c.watches) {
+				fmt.Println("No watch with the number", arg)
+				continue
+			}
+			c.watches = slices.Delete(c.watches, num-1, num)
+		case "list", "l":
+			c.printSource(session.Line(), 3)
+		default:
+			fmt.Printf("Unknown command %s, type help for the commands.\n", command)
+		}
+	}
+}
+
+func (c *cliDebugger) changeBreakpoint(arg string, add bool) {
+	line, err := strconv.Atoi(arg)
+	if err != nil || line < 1 || line > len(c.sourceLines) {
+		fmt.Println("Invalid line number", arg)
+		return
+	}
+	c.breakpoints = slices.DeleteFunc(c.breakpoints, func(l int) bool { return l == line })
+	if add {
+		c.breakpoints = append(c.breakpoints, line)
+		slices.Sort(c.breakpoints)
+	}
+	c.debugger.SetBreakpoints(c.breakpoints)
+}
+
+// prints the line with the given count of lines around it, the current line is marked
+func (c *cliDebugger) printSource(line int, around int) {
+	for l := max(line-around, 1); l <= min(line+around, len(c.sourceLines)); l++ {
+		marker := " "
+		if l == line {
+			marker = ">"
+		}
+		fmt.Printf("%s %4d | %s\n", marker, l, c.sourceLines[l-1])
+	}
+}
+
+func evaluateForDisplay(session *lox.DebugSession, expression string) string {
+	result, err := session.Evaluate(expression, 0)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return result
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"golox/lox"
+)
+
+// answers every pause with the next action, and records where it paused and what it saw
+type testDebugHost struct {
+	actions []lox.DebugAction
+	pauses  []string
+	scopes  [][]lox.Scope
+	stacks  [][]lox.StackFrame
+	watches []string
+	watched []string
+}
+
+func (h *testDebugHost) Paused(session *lox.DebugSession, reason string) lox.DebugAction {
+	h.pauses = append(h.pauses, fmt.Sprintf("%s %d", reason, session.Line()))
+	h.scopes = append(h.scopes, session.Scopes(0))
+	h.stacks = append(h.stacks, session.CallStack())
+	for _, watch := range h.watches {
+		result, err := session.Evaluate(watch, 0)
+		if err != nil {
+			result = "error: " + err.Error()
+		}
+		h.watched = append(h.watched, result)
+	}
+	if len(h.actions) == 0 {
+		return lox.DebugContinue
+	}
+	action := h.actions[0]
+	h.actions = h.actions[1:]
+	return action
+}
+
+func runDebugger(t *testing.T, code string, host *testDebugHost, breakpoints []int, stopOnEntry bool) []string {
+	t.Helper()
+	var output []string
+	lox.SetLogger(lox.Logger{
+		Print: func(s string) {
+			output = append(output, s)
+		},
+	})
+	defer lox.SetLogger(lox.Logger{})
+	lox.ResetErrorState()
+	defer lox.ResetErrorState()
+
+	debugger := lox.NewDebugger(host)
+	debugger.SetBreakpoints(breakpoints)
+	if exitCode := debugger.Run([]byte(code), context.Background(), stopOnEntry); exitCode != 0 {
+		t.Fatalf("expected the program to finish, got exit code %d", exitCode)
+	}
+	return output
+}
+
+func TestDebuggerStepping(t *testing.T) {
+	host := &testDebugHost{
+		actions: []lox.DebugAction{lox.DebugStepOver, lox.DebugStepOver, lox.DebugStepInto, lox.DebugStepOut},
+	}
+	output := runDebugger(t, dapTestProgram, host, nil, true)
+
+	expected := []string{"entry 1", "step 6", "step 7", "step 2", "step 8"}
+	if !slices.Equal(host.pauses, expected) {
+		t.Errorf("expected the pauses %v, got %v", expected, host.pauses)
+	}
+	if !slices.Equal(output, []string{"15", "done"}) {
+		t.Errorf("unexpected output %v", output)
+	}
+	inAdd := host.stacks[3]
+	if len(inAdd) != 2 || inAdd[0].Name != "add" || inAdd[0].Line != 2 || inAdd[1].Name != "<script>" || inAdd[1].Line != 7 {
+		t.Errorf("unexpected call stack inside add %+v", inAdd)
+	}
+}
+
+func TestDebuggerScopes(t *testing.T) {
+	host := &testDebugHost{watches: []string{"a * x"}}
+	runDebugger(t, dapTestProgram, host, []int{3}, false)
+
+	if !slices.Equal(host.pauses, []string{"breakpoint 3"}) {
+		t.Fatalf("expected to pause once at the breakpoint, got %v", host.pauses)
+	}
+	var scopes []string
+	for _, scope := range host.scopes[0] {
+		var variables []string
+		for _, variable := range scope.Variables {
+			variables = append(variables, variable.Name+"="+variable.Value)
+		}
+		scopes = append(scopes, scope.Name+": "+strings.Join(variables, " "))
+	}
+	// the natives and the constants PI and E would be in every dump, so they're left out
+	expected := []string{"locals: sum=15", "enclosing: a=10 b=5", "globals: add=<fn add> x=10"}
+	if !slices.Equal(scopes, expected) {
+		t.Errorf("expected the scopes %q, got %q", expected, scopes)
+	}
+	if !slices.Equal(host.watched, []string{"100"}) {
+		t.Errorf("expected the watch to be 100, got %v", host.watched)
+	}
+}
+
+// a global the program assigns over PI isn't a native anymore
+func TestDebuggerReassignedConstant(t *testing.T) {
+	host := &testDebugHost{}
+	runDebugger(t, "PI = 3;\nprint PI;\n", host, []int{2}, false)
+
+	if len(host.scopes) != 1 {
+		t.Fatalf("expected to pause once, got %v", host.pauses)
+	}
+	globals := host.scopes[0][len(host.scopes[0])-1].Variables
+	if !slices.Equal(globals, []lox.Variable{{Name: "PI", Value: "3"}}) {
+		t.Errorf("expected only PI in the globals, got %v", globals)
+	}
+}
+
+// functions called by a watch see their own globals, not the paused frame's locals
+func TestDebuggerEvaluateCalls(t *testing.T) {
+	code := `var x = 1;
+fun show() { print "shown"; return 2; }
+fun g() { return x; }
+fun h() {
+  var x = 99;
+  print g();
+}
+h();
+`
+	host := &testDebugHost{watches: []string{"x", "g()", "show()", "x = 5", "undefined"}}
+	output := runDebugger(t, code, host, []int{6}, false)
+
+	expected := []string{"99", "1", "2", "5", "error: Undefined variable 'undefined'."}
+	if !slices.Equal(host.watched, expected) {
+		t.Errorf("expected the watches %q, got %q", expected, host.watched)
+	}
+	if !slices.Equal(output, []string{"shown", "1"}) {
+		t.Errorf("expected the print of the watch and of the program, got %v", output)
+	}
+}
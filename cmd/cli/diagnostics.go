@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"golox/lox"
+)
+
+/*
+a diagnostic as it's printed with --diagnostics=json, one object per line. Lines
+and columns start at 1 and the end column is just after the span. Errors have a
+code for their phase, warnings and lint findings the code of what they check.
+*/
+type jsonDiagnostic struct {
+	Phase       string           `json:"phase"` // scan, parse, resolve, runtime, lint, or internal for bugs in golox
+	Severity    string           `json:"severity"`
+	Code        string           `json:"code"`
+	Message     string           `json:"message"`
+	File        string           `json:"file"`
+	StartLine   int              `json:"startLine"`
+	StartColumn int              `json:"startColumn"`
+	EndLine     int              `json:"endLine"`
+	EndColumn   int              `json:"endColumn"`
+	Stack       []jsonStackFrame `json:"stack,omitempty"`      // for runtime errors, innermost first
+	Suggestion  string           `json:"suggestion,omitempty"` // for undefined names, the closest defined one
+}
+
+type jsonStackFrame struct {
+	Function string `json:"function"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+type diagnosticWriter struct {
+	filename string
+	encoder  *json.Encoder
+}
+
+func newDiagnosticWriter(filename string, out io.Writer) *diagnosticWriter {
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false) // keeps "<script>" in stack traces readable
+	return &diagnosticWriter{filename: filename, encoder: encoder}
+}
+
+func (w *diagnosticWriter) write(d jsonDiagnostic) {
+	d.File = w.filename
+	w.encoder.Encode(d)
+}
+
+// a diagnostic for the token, which spans lines for multiline strings. The end of the file is an empty span.
+func tokenDiagnostic(phase string, severity string, code string, token lox.TokenLogMeta, msg string) jsonDiagnostic {
+	d := jsonDiagnostic{
+		Phase: phase, Severity: severity, Code: code, Message: msg,
+		StartLine: token.Line, StartColumn: token.StartCol, EndLine: token.Line, EndColumn: max(token.Col, token.StartCol),
+	}
+	if token.StartLine > 0 && token.StartLine < token.Line {
+		d.StartLine, d.EndColumn = token.StartLine, token.Col
+	}
+	return d
+}
+
+// replaces the error and warning callbacks of the logger with ones writing json
+func (w *diagnosticWriter) logger(logger lox.Logger) lox.Logger {
+	logger.ScanError = func(line int, col int, msg string) {
+		// the column is just after the character with the error
+		token := lox.TokenLogMeta{Line: line, Col: max(col, 2), StartCol: max(col-1, 1)}
+		w.write(tokenDiagnostic("scan", "error", "scan-error", token, msg))
+	}
+	logger.ParseError = func(token lox.TokenLogMeta, msg string) {
+		w.write(tokenDiagnostic("parse", "error", "syntax-error", token, msg))
+	}
+	logger.ResolveError = func(token lox.TokenLogMeta, msg string) {
+		w.write(tokenDiagnostic("resolve", "error", "resolve-error", token, msg))
+	}
+	logger.RuntimeError = func(token lox.TokenLogMeta, msg string) {
+		d := tokenDiagnostic("runtime", "error", "runtime-error", token, msg)
+		d.Suggestion = token.Suggestion
+		for _, frame := range token.Stack {
+			d.Stack = append(d.Stack, jsonStackFrame{Function: frame.Name, Line: frame.Line, Column: frame.Column})
+		}
+		w.write(d)
+	}
+	logger.InternalError = func(msg string) {
+		w.write(jsonDiagnostic{Phase: "internal", Severity: "error", Code: "internal-error", Message: msg})
+	}
+	if logger.Warning != nil {
+		logger.Warning = func(token lox.TokenLogMeta, severity lox.Severity, code string, msg string) {
+			w.write(tokenDiagnostic(warningPhase(code), strings.ToLower(severity.String()), code, token, msg))
+		}
+	}
+	return logger
+}
+
+// warnings with the code of a lint rule, like unused-local, come from the lint pass
+func warningPhase(code string) string {
+	for _, rule := range lox.LintRules {
+		if rule.Name == code {
+			return "lint"
+		}
+	}
+	return "resolve"
+}
+
+func (w *diagnosticWriter) lintWarning(warning lox.LintWarning) {
+	location := warning.Location
+	w.write(jsonDiagnostic{
+		Phase: "lint", Severity: "warning", Code: warning.Rule, Message: warning.Message,
+		StartLine: location.Line, StartColumn: location.Column, EndLine: location.Line, EndColumn: location.EndColumn,
+	})
+}
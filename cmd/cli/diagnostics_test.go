@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"golox/lox"
+)
+
+// runs the code with the diagnostics written as json, and gives them
+func runWithJSONDiagnostics(t *testing.T, code string, warnings bool) []jsonDiagnostic {
+	t.Helper()
+	var out bytes.Buffer
+	writer := newDiagnosticWriter("program.lox", &out)
+	logger := lox.Logger{Print: func(string) {}}
+	if warnings {
+		logger.Warning = func(lox.TokenLogMeta, lox.Severity, string, string) {}
+	}
+	lox.SetLogger(writer.logger(logger))
+	defer lox.SetLogger(lox.Logger{})
+	lox.ResetErrorState()
+	lox.Run([]byte(code), context.Background())
+	lox.ResetErrorState()
+
+	var diagnostics []jsonDiagnostic
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var d jsonDiagnostic
+		if err := json.Unmarshal([]byte(line), &d); err != nil {
+			t.Fatalf("invalid json %q: %v", line, err)
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	return diagnostics
+}
+
+func TestJSONDiagnosticsPhases(t *testing.T) {
+	cases := []struct {
+		code     string
+		expected jsonDiagnostic
+	}{
+		{"print @;", jsonDiagnostic{Phase: "scan", Code: "scan-error", StartColumn: 7, EndColumn: 8}},
+		{"print 1", jsonDiagnostic{Phase: "parse", Code: "syntax-error", StartColumn: 8, EndColumn: 8}},
+		{"print this;", jsonDiagnostic{Phase: "resolve", Code: "resolve-error", StartColumn: 7, EndColumn: 11}},
+		{"print -\"a\";", jsonDiagnostic{Phase: "runtime", Code: "runtime-error", StartColumn: 7, EndColumn: 8}},
+	}
+	for _, c := range cases {
+		d := runWithJSONDiagnostics(t, c.code, false)[0]
+		if d.Phase != c.expected.Phase || d.Code != c.expected.Code || d.Severity != "error" || d.File != "program.lox" ||
+			d.StartLine != 1 || d.EndLine != 1 || d.StartColumn != c.expected.StartColumn || d.EndColumn != c.expected.EndColumn {
+			t.Errorf("unexpected diagnostic for %q: %+v", c.code, d)
+		}
+	}
+}
+
+func TestJSONDiagnosticsStackTrace(t *testing.T) {
+	code := `fun inner() {
+  return 1 + nil;
+}
+fun outer() {
+  return inner();
+}
+outer();
+`
+	d := runWithJSONDiagnostics(t, code, false)[0]
+	expected := []jsonStackFrame{{"inner", 2, 12}, {"outer", 5, 16}, {"<script>", 7, 7}}
+	if d.Message != "Operands must be two numbers or two strings." || len(d.Stack) != len(expected) {
+		t.Fatalf("unexpected diagnostic %+v", d)
+	}
+	for idx, frame := range expected {
+		if d.Stack[idx] != frame {
+			t.Errorf("expected frame %d to be %+v, got %+v", idx, frame, d.Stack[idx])
+		}
+	}
+}
+
+// callbacks of native methods like map are on the stack, called from where the method was
+func TestJSONDiagnosticsCallbackStackTrace(t *testing.T) {
+	code := `fun double(x) {
+  return x * nil;
+}
+fun each(list) {
+  list.map(double);
+}
+[[1]].forEach(each);
+`
+	d := runWithJSONDiagnostics(t, code, false)[0]
+	expected := []jsonStackFrame{{"double", 2, 12}, {"map", 5, 18}, {"each", 5, 18}, {"forEach", 7, 19}, {"<script>", 7, 19}}
+	if d.Message != "Operands must be numbers." || len(d.Stack) != len(expected) {
+		t.Fatalf("unexpected diagnostic %+v", d)
+	}
+	for idx, frame := range expected {
+		if d.Stack[idx] != frame {
+			t.Errorf("expected frame %d to be %+v, got %+v", idx, frame, d.Stack[idx])
+		}
+	}
+}
+
+func TestJSONDiagnosticsWarnings(t *testing.T) {
+	diagnostics := runWithJSONDiagnostics(t, "{\n  var a = 1;\n}\nprint \"a\" + nil;\n", true)
+	if len(diagnostics) != 2 {
+		t.Fatalf("expected two warnings, got %+v", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Phase != "resolve" || d.Severity != "warning" || d.Code != lox.WarnStringCoercion || d.StartLine != 4 || d.StartColumn != 11 {
+		t.Errorf("unexpected warning %+v", d)
+	}
+	// unused locals are found by the lint rule
+	d = diagnostics[1]
+	if d.Phase != "lint" || d.Severity != "warning" || d.Code != lox.WarnUnusedLocal || d.StartLine != 2 || d.StartColumn != 7 {
+		t.Errorf("unexpected warning %+v", d)
+	}
+}
+
+func TestJSONDiagnosticsMultilineString(t *testing.T) {
+	// the string token starts on the first line and ends on the second
+	d := runWithJSONDiagnostics(t, "print 1 \"one\ntwo\";\n", false)[0]
+	if d.StartLine != 1 || d.StartColumn != 9 || d.EndLine != 2 || d.EndColumn != 5 {
+		t.Errorf("expected the diagnostic to span the string, got %+v", d)
+	}
+
+	// columns after the string are counted from the start of its last line
+	d = runWithJSONDiagnostics(t, "var s = \"a\nbc\"; print s + nope;\n", false)[0]
+	if d.StartLine != 2 || d.StartColumn != 16 || d.EndLine != 2 || d.EndColumn != 20 {
+		t.Errorf("unexpected diagnostic for the undefined name %+v", d)
+	}
+}
+
+func TestJSONDiagnosticsIncompleteCode(t *testing.T) {
+	diagnostics := runWithJSONDiagnostics(t, "var x = 1;\nprint x +", false)
+	if len(diagnostics) != 1 || diagnostics[0].Code != "syntax-error" || diagnostics[0].Message != "Error at end: Expect expression." {
+		t.Errorf("expected only the missing operand to be reported, got %+v", diagnostics)
+	}
+}
+
+// a bug in golox is reported as json too, so the output stays machine readable
+func TestJSONDiagnosticsInternalError(t *testing.T) {
+	var out bytes.Buffer
+	logger := newDiagnosticWriter("program.lox", &out).logger(lox.Logger{})
+	logger.InternalError("runtime error: index out of range [9] with length 9")
+
+	var d jsonDiagnostic
+	if err := json.Unmarshal(out.Bytes(), &d); err != nil {
+		t.Fatalf("invalid json %q: %v", out.String(), err)
+	}
+	if d.Phase != "internal" || d.Code != "internal-error" || d.Severity != "error" || d.File != "program.lox" {
+		t.Errorf("unexpected diagnostic %+v", d)
+	}
+}
+
+// a string left open inside an interpolation leaves the outer string open too, it's reported once
+func TestJSONDiagnosticsUnterminatedInterpolation(t *testing.T) {
+	var scanErrors []jsonDiagnostic
+	for _, d := range runWithJSONDiagnostics(t, "print \"a ${b\";", false) {
+		if d.Phase == "scan" {
+			scanErrors = append(scanErrors, d)
+		}
+	}
+	if len(scanErrors) != 1 || scanErrors[0].Message != "Error: Unterminated string." {
+		t.Errorf("expected the unterminated string to be reported once, got %+v", scanErrors)
+	}
+}
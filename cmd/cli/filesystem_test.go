@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"golox/lox"
+)
+
+func TestMemoryFileSystem(t *testing.T) {
+	files := lox.NewMemoryFileSystem()
+	if err := files.WriteFile("a/b.txt", []byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	if err := files.AppendFile("./a/c/d.txt", []byte("d")); err != nil {
+		t.Fatal(err)
+	}
+	if err := files.AppendFile("a/c/d.txt", []byte("e")); err != nil {
+		t.Fatal(err)
+	}
+	files.WriteFile("top.txt", nil)
+
+	if data, err := files.ReadFile("a/c/../c/d.txt"); err != nil || string(data) != "de" {
+		t.Errorf("expected to read \"de\", got %q and the error %v", data, err)
+	}
+	for _, name := range []string{".", "a", "a/", "a/c", "a/b.txt", "top.txt"} {
+		if !files.Exists(name) {
+			t.Errorf("expected %q to exist", name)
+		}
+	}
+	for _, name := range []string{"b.txt", "a/b", "a/c/d"} {
+		if files.Exists(name) {
+			t.Errorf("expected %q not to exist", name)
+		}
+	}
+
+	listings := map[string][]string{
+		".":   {"a", "top.txt"},
+		"a":   {"b.txt", "c"},
+		"a/c": {"d.txt"},
+	}
+	for dir, expected := range listings {
+		if entries, err := files.ListDir(dir); err != nil || !slices.Equal(entries, expected) {
+			t.Errorf("expected %q to have the entries %v, got %v and the error %v", dir, expected, entries, err)
+		}
+	}
+	if _, err := files.ListDir("a/b.txt/x"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected listing a missing directory to fail with fs.ErrNotExist, got %v", err)
+	}
+
+	// the content given and read can be changed without changing the file
+	data := []byte("x")
+	files.WriteFile("x.txt", data)
+	data[0] = 'y'
+	read, _ := files.ReadFile("x.txt")
+	read[0] = 'z'
+	if read, _ := files.ReadFile("x.txt"); string(read) != "x" {
+		t.Errorf("expected the file to still be \"x\", got %q", read)
+	}
+
+	if err := files.Remove("a/c/d.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if files.Exists("a/c") {
+		t.Error("expected the directory to be gone with its last file")
+	}
+	if _, err := files.ReadFile("a/c/d.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected reading a removed file to fail with fs.ErrNotExist, got %v", err)
+	}
+	if err := files.Remove("a/c/d.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected removing a removed file to fail with fs.ErrNotExist, got %v", err)
+	}
+}
+
+// the file natives give the same results with the files in memory, like in the playground
+func TestFileIOInMemory(t *testing.T) {
+	code, err := os.ReadFile("../../test/extensions/file_io.lox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected, output []string
+	for _, line := range strings.Split(string(code), "\n") {
+		if _, expect, ok := strings.Cut(line, "// expect: "); ok {
+			expected = append(expected, expect)
+		}
+	}
+	lox.SetLogger(lox.Logger{
+		Print: func(s string) {
+			output = append(output, strings.Split(s, "\n")...)
+		},
+	})
+	defer lox.SetLogger(lox.Logger{})
+	lox.SetFileSystem(lox.NewMemoryFileSystem())
+	defer lox.SetFileSystem(nil)
+	lox.ResetErrorState()
+
+	if exitCode := lox.Run(code, context.Background()); exitCode != 0 {
+		t.Fatalf("expected the program to run, got exit code %d", exitCode)
+	}
+	if !slices.Equal(output, expected) {
+		t.Errorf("expected the output %q, got %q", expected, output)
+	}
+}
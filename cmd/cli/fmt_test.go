@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golox/lox"
+)
+
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		name, code, expected string
+	}{
+		{
+			"spacing and indentation",
+			"var a=1+2*3;\nfun f(x,y){return x-y;}\nif(a>1){print a;}else{print -a;}\n",
+			"var a = 1 + 2 * 3;\nfun f(x, y) {\n  return x - y;\n}\nif (a > 1) {\n  print a;\n} else {\n  print -a;\n}\n",
+		},
+		{
+			"comments and blank lines",
+			"// header\n\n\n\nvar a = 1; // one\n// before b\nvar b = 2;\n\n\nclass A {\n  // empty\n}\n",
+			"// header\n\nvar a = 1; // one\n// before b\nvar b = 2;\n\nclass A {\n  // empty\n}\n",
+		},
+		{
+			"loops",
+			"for(var i=0;i<3;i=i+1) print i;\nfor(;;){}\nwhile (true)\n{ break; }\n",
+			"for (var i = 0; i < 3; i = i + 1) print i;\nfor (;;) {}\nwhile (true) {\n  break;\n}\n",
+		},
+		{
+			"long calls are wrapped",
+			"print f(\"" + strings.Repeat("a", 50) + "\", \"" + strings.Repeat("b", 50) + "\");\n",
+			"print f(\n  \"" + strings.Repeat("a", 50) + "\",\n  \"" + strings.Repeat("b", 50) + "\"\n);\n",
+		},
+		{
+			"strings are kept as written",
+			"print \"a \\${b}\";\nprint \"a${\"b\"}c ${x+1}\";\n",
+			"print \"a \\${b}\";\nprint \"a${\"b\"}c ${x + 1}\";\n",
+		},
+		{
+			"comments before else stay after the then branch",
+			"if (x) print 1; // trailing\nelse { print 2; }\nif (x) { print 1; }\n// own line\nelse print 2;\n",
+			"if (x) print 1; // trailing\nelse {\n  print 2;\n}\nif (x) {\n  print 1;\n}\n// own line\nelse print 2;\n",
+		},
+		{
+			"trailing comment at the end of the file",
+			"print 1;\nprint 2; // two",
+			"print 1;\nprint 2; // two\n",
+		},
+	}
+	for _, c := range cases {
+		formatted, exitCode := lox.Format([]byte(c.code))
+		if exitCode != 0 || string(formatted) != c.expected {
+			t.Errorf("%s: expected\n%s\ngot exit code %d and\n%s", c.name, c.expected, exitCode, formatted)
+		}
+	}
+}
+
+// formatting the lox tests again shouldn't change them
+func TestFormatIsIdempotent(t *testing.T) {
+	lox.SetLogger(lox.Logger{
+		ScanError:  func(int, int, string) {},
+		ParseError: func(lox.TokenLogMeta, string) {},
+	})
+	defer lox.SetLogger(lox.Logger{})
+	err := filepath.WalkDir("../../test", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".lox" {
+			return err
+		}
+		code, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		lox.ResetErrorState()
+		formatted, exitCode := lox.Format(code)
+		if exitCode != 0 {
+			return nil // tests for syntax errors
+		}
+		again, _ := lox.Format(formatted)
+		if !bytes.Equal(formatted, again) {
+			t.Errorf("formatting %s again changed it from\n%s\nto\n%s", path, formatted, again)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lox.ResetErrorState()
+}
+
+func TestFormatFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "program.lox")
+	code := []byte("print  1;\n")
+	if err := os.WriteFile(filename, code, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if exitCode := formatFile(filename, code, true); exitCode != 1 {
+		t.Errorf("expected --check to fail for an unformatted file, got %d", exitCode)
+	}
+	if contents, _ := os.ReadFile(filename); !bytes.Equal(contents, code) {
+		t.Errorf("expected --check not to change the file, got %q", contents)
+	}
+
+	if exitCode := formatFile(filename, code, false); exitCode != 0 {
+		t.Errorf("expected formatting to succeed, got %d", exitCode)
+	}
+	contents, _ := os.ReadFile(filename)
+	if string(contents) != "print 1;\n" {
+		t.Errorf("expected the file to be formatted, got %q", contents)
+	}
+	if exitCode := formatFile(filename, contents, true); exitCode != 0 {
+		t.Errorf("expected --check to pass for a formatted file, got %d", exitCode)
+	}
+}
+
+// unfinished code is reported rather than formatted, and not half formatted
+func TestFormatIncomplete(t *testing.T) {
+	var errors []string
+	lox.SetLogger(lox.Logger{
+		ParseError: func(token lox.TokenLogMeta, msg string) {
+			errors = append(errors, msg)
+		},
+	})
+	defer lox.SetLogger(lox.Logger{})
+	lox.ResetErrorState()
+	defer lox.ResetErrorState()
+
+	formatted, exitCode := lox.Format([]byte("var x = 1;\nprint x +"))
+	if exitCode != 65 || formatted != nil || len(errors) != 1 || errors[0] != "Error at end: Expect expression." {
+		t.Errorf("expected the missing operand to be reported, got exit code %d, %q and the errors %v", exitCode, formatted, errors)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+)
+
+/*
+Both the debug adapter and the language server protocols frame messages with
+headers like http, of which only Content-Length is used, followed by the JSON content.
+*/
+func readMessage(reader *bufio.Reader) ([]byte, error) {
+	headers, err := textproto.NewReader(reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length header: %w", err)
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(reader, content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func writeMessage(writer io.Writer, content []byte) error {
+	_, err := fmt.Fprintf(writer, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	return err
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadLine(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader("a line with spaces \nwindows\r\n\nno newline"))
+	expected := []string{"a line with spaces ", "windows", "", "no newline"}
+	for _, want := range expected {
+		line, err := readLine(reader)
+		if err != nil || line != want {
+			t.Errorf("expected %q, got %q and the error %v", want, line, err)
+		}
+	}
+	if line, err := readLine(reader); err != io.EOF {
+		t.Errorf("expected io.EOF at the end of the input, got %q and the error %v", line, err)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"golox/lox"
+)
+
+const lintTestProgram = `var total = 0;
+fun add(a, b, unused) {
+  var sum = a + b;
+  var never = 1;
+  return sum;
+  print "after";
+}
+fun shadow(_total) {
+  { var total = 2; print total; }
+}
+class Point {
+  init(x, y) { this.x = x; this.y = y; }
+  move() { this.x = this.x; }
+}
+class Point3 < Point {}
+var p = Point(1);
+var q = Point3(1, 2);
+var f = add;
+f(1);
+len("a", "b");
+"abc"();
+total = total;
+print 1 == "1";
+var ignored = 1; // lox-ignore
+fun g(y) {
+  // lox-ignore unused-local
+  var z = 1;
+  var w = y; // lox-ignore shadowing
+}
+`
+
+// the warnings as "line:column rule"
+func lint(t *testing.T, code string, disabled map[string]bool) []string {
+	t.Helper()
+	warnings, exitCode := lox.Lint([]byte(code), disabled)
+	if exitCode != 0 {
+		t.Fatalf("expected the program to be linted, got exit code %d", exitCode)
+	}
+	var result []string
+	for _, warning := range warnings {
+		result = append(result, fmt.Sprintf("%d:%d %s", warning.Location.Line, warning.Location.Column, warning.Rule))
+	}
+	return result
+}
+
+func TestLint(t *testing.T) {
+	expected := []string{
+		"2:15 unused-parameter",
+		"4:7 unused-local",
+		"6:3 unreachable-code",
+		"9:9 shadowing",
+		"13:17 no-effect-assignment",
+		"16:16 argument-count",
+		"20:13 argument-count",
+		"21:7 not-callable",
+		"22:1 no-effect-assignment",
+		"23:9 mixed-comparison",
+		"28:7 unused-local",
+	}
+	if warnings := lint(t, lintTestProgram, nil); !slices.Equal(warnings, expected) {
+		t.Errorf("expected the warnings\n%v\ngot\n%v", expected, warnings)
+	}
+
+	disabled := map[string]bool{"unused-local": true, "argument-count": true, "shadowing": true}
+	expected = []string{
+		"2:15 unused-parameter",
+		"6:3 unreachable-code",
+		"13:17 no-effect-assignment",
+		"21:7 not-callable",
+		"22:1 no-effect-assignment",
+		"23:9 mixed-comparison",
+	}
+	if warnings := lint(t, lintTestProgram, disabled); !slices.Equal(warnings, expected) {
+		t.Errorf("expected the warnings without the disabled rules\n%v\ngot\n%v", expected, warnings)
+	}
+}
+
+func TestLintArgumentCount(t *testing.T) {
+	// redeclared and reassigned functions could be anything when they're called
+	code := `fun f(a) {}
+fun f(a, b) {}
+f(1);
+fun h() {}
+h(1);
+h = f;
+class A < B {}
+class B < A {}
+A(1);
+{
+  fun local(a) {}
+  local();
+}
+`
+	if warnings := lint(t, code, map[string]bool{"unused-parameter": true}); !slices.Equal(warnings, []string{"12:9 argument-count"}) {
+		t.Errorf("expected only the call of the local function to be reported, got %v", warnings)
+	}
+}
+
+func TestLintIncomplete(t *testing.T) {
+	var errors []string
+	lox.SetLogger(lox.Logger{
+		ParseError: func(token lox.TokenLogMeta, msg string) {
+			errors = append(errors, msg)
+		},
+	})
+	defer lox.SetLogger(lox.Logger{})
+	lox.ResetErrorState()
+	defer lox.ResetErrorState()
+
+	warnings, exitCode := lox.Lint([]byte("var x = 1;\nprint x +"), nil)
+	if exitCode != 65 || len(warnings) != 0 || len(errors) != 1 || errors[0] != "Error at end: Expect expression." {
+		t.Errorf("expected the missing operand to be reported, got exit code %d, %v and the errors %v", exitCode, warnings, errors)
+	}
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"golox/lox"
+)
+
+/*
+Language Server Protocol server over stdio, giving editors diagnostics, go to
+definition, find references, hover, outline and completion for lox files -
+https://microsoft.github.io/language-server-protocol/
+
+Every change sends the whole document, which is analysed again, as lox programs
+are small enough for it. Positions are 0 based lines and characters in the protocol.
+The characters are bytes like the columns of the tokens if the client takes utf-8,
+otherwise they're UTF-16 code units, the protocol's default, and are converted.
+*/
+type lspServer struct {
+	reader     *bufio.Reader
+	writer     io.Writer
+	documents  map[string]*lspDocument // by uri, while they're open in the editor
+	utf8       bool                    // the characters of positions are bytes
+	isShutdown bool
+}
+
+type lspDocument struct {
+	analysis *lox.Analysis
+	lines    []string // to convert between bytes and UTF-16 code units
+}
+
+type lspMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"` // missing for notifications
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type lspResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result"`
+	Error   *lspError       `json:"error,omitempty"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type lspPositionParams struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Position lspPosition `json:"position"`
+}
+
+const (
+	lspInvalidRequest = -32600
+	lspMethodNotFound = -32601
+	lspInvalidParams  = -32602
+)
+
+// serves requests till the client sends exit or closes the input
+func serveLSP(in io.Reader, out io.Writer) error {
+	s := &lspServer{
+		reader:    bufio.NewReader(in),
+		writer:    out,
+		documents: make(map[string]*lspDocument),
+	}
+	for {
+		content, err := readMessage(s.reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var msg lspMessage
+		if err := json.Unmarshal(content, &msg); err != nil {
+			return fmt.Errorf("invalid message: %w", err)
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		s.handle(msg)
+	}
+}
+
+func (s *lspServer) write(message any) {
+	content, _ := json.Marshal(message)
+	writeMessage(s.writer, content)
+}
+
+func (s *lspServer) respond(msg lspMessage, result any) {
+	s.write(lspResponse{JSONRPC: "2.0", ID: msg.ID, Result: result})
+}
+
+func (s *lspServer) respondError(msg lspMessage, code int, format string, args ...any) {
+	s.write(lspResponse{JSONRPC: "2.0", ID: msg.ID, Error: &lspError{Code: code, Message: fmt.Sprintf(format, args...)}})
+}
+
+func (s *lspServer) notify(method string, params any) {
+	s.write(map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+func (s *lspServer) handle(msg lspMessage) {
+	isRequest := msg.ID != nil
+	if s.isShutdown && isRequest {
+		s.respondError(msg, lspInvalidRequest, "The server is shut down.")
+		return
+	}
+	switch msg.Method {
+	case "initialize":
+		var params struct {
+			Capabilities struct {
+				General struct {
+					PositionEncodings []string `json:"positionEncodings"`
+				} `json:"general"`
+			} `json:"capabilities"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		s.utf8 = slices.Contains(params.Capabilities.General.PositionEncodings, "utf-8")
+		encoding := "utf-16"
+		if s.utf8 {
+			encoding = "utf-8"
+		}
+		s.respond(msg, map[string]any{
+			"capabilities": map[string]any{
+				"positionEncoding":       encoding,
+				"textDocumentSync":       map[string]any{"openClose": true, "change": 1}, // the whole document
+				"definitionProvider":     true,
+				"referencesProvider":     true,
+				"hoverProvider":          true,
+				"documentSymbolProvider": true,
+				"completionProvider":     map[string]any{"triggerCharacters": []string{"."}},
+			},
+			"serverInfo": map[string]any{"name": "golox"},
+		})
+	case "shutdown":
+		s.isShutdown = true
+		s.respond(msg, nil)
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if json.Unmarshal(msg.Params, &params) == nil {
+			s.analyze(params.TextDocument.URI, params.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if json.Unmarshal(msg.Params, &params) == nil && len(params.ContentChanges) > 0 {
+			s.analyze(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+		}
+	case "textDocument/didClose":
+		var params lspPositionParams
+		if json.Unmarshal(msg.Params, &params) == nil {
+			delete(s.documents, params.TextDocument.URI)
+			s.notify("textDocument/publishDiagnostics", map[string]any{
+				"uri": params.TextDocument.URI, "diagnostics": []any{},
+			})
+		}
+	case "textDocument/definition":
+		s.withPosition(msg, s.definition)
+	case "textDocument/references":
+		s.withPosition(msg, s.references)
+	case "textDocument/hover":
+		s.withPosition(msg, s.hover)
+	case "textDocument/completion":
+		s.withPosition(msg, s.completion)
+	case "textDocument/documentSymbol":
+		var params lspPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			s.respondError(msg, lspInvalidParams, "Invalid params: %v", err)
+			return
+		}
+		var symbols []map[string]any
+		if doc := s.documents[params.TextDocument.URI]; doc != nil {
+			symbols = s.documentSymbols(doc, doc.analysis.Symbols)
+		}
+		s.respond(msg, symbols)
+	default:
+		// notifications which aren't supported, like $/cancelRequest, can be ignored
+		if isRequest {
+			s.respondError(msg, lspMethodNotFound, "Unsupported method '%s'.", msg.Method)
+		}
+	}
+}
+
+func (s *lspServer) analyze(uri string, text string) {
+	doc := &lspDocument{analysis: lox.Analyze([]byte(text)), lines: strings.Split(text, "\n")}
+	s.documents[uri] = doc
+	diagnostics := []map[string]any{}
+	for _, diagnostic := range doc.analysis.Diagnostics {
+		diagnostics = append(diagnostics, map[string]any{
+			"range":    s.toLSPRange(doc, diagnostic.Location),
+			"severity": 1, // error
+			"source":   "golox",
+			"message":  diagnostic.Message,
+		})
+	}
+	s.notify("textDocument/publishDiagnostics", map[string]any{"uri": uri, "diagnostics": diagnostics})
+}
+
+// calls handler with the document and the position as the line and column of the tokens
+func (s *lspServer) withPosition(msg lspMessage, handler func(msg lspMessage, params json.RawMessage, uri string, doc *lspDocument, line int, column int)) {
+	var params lspPositionParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		s.respondError(msg, lspInvalidParams, "Invalid params: %v", err)
+		return
+	}
+	uri := params.TextDocument.URI
+	doc := s.documents[uri]
+	if doc == nil {
+		s.respond(msg, nil)
+		return
+	}
+	handler(msg, msg.Params, uri, doc, params.Position.Line+1, s.column(doc, params.Position))
+}
+
+func (s *lspServer) definition(msg lspMessage, _ json.RawMessage, uri string, doc *lspDocument, line int, column int) {
+	location, ok := doc.analysis.DefinitionAt(line, column)
+	if !ok {
+		s.respond(msg, nil)
+		return
+	}
+	s.respond(msg, lspLocation{URI: uri, Range: s.toLSPRange(doc, location)})
+}
+
+func (s *lspServer) references(msg lspMessage, rawParams json.RawMessage, uri string, doc *lspDocument, line int, column int) {
+	var params struct {
+		Context struct {
+			IncludeDeclaration bool `json:"includeDeclaration"`
+		} `json:"context"`
+	}
+	json.Unmarshal(rawParams, &params)
+	locations := []lspLocation{}
+	for _, location := range doc.analysis.ReferencesAt(line, column, params.Context.IncludeDeclaration) {
+		locations = append(locations, lspLocation{URI: uri, Range: s.toLSPRange(doc, location)})
+	}
+	s.respond(msg, locations)
+}
+
+func (s *lspServer) hover(msg lspMessage, _ json.RawMessage, _ string, doc *lspDocument, line int, column int) {
+	detail, location, ok := doc.analysis.HoverAt(line, column)
+	if !ok {
+		s.respond(msg, nil)
+		return
+	}
+	s.respond(msg, map[string]any{
+		"contents": map[string]any{"kind": "markdown", "value": "```lox\n" + detail + "\n```"},
+		"range":    s.toLSPRange(doc, location),
+	})
+}
+
+func (s *lspServer) completion(msg lspMessage, _ json.RawMessage, _ string, doc *lspDocument, line int, column int) {
+	items := []map[string]any{}
+	for _, completion := range doc.analysis.Completions(line, column) {
+		items = append(items, map[string]any{
+			"label":  completion.Label,
+			"kind":   completionKind(completion.Kind),
+			"detail": completion.Detail,
+		})
+	}
+	s.respond(msg, items)
+}
+
+func (s *lspServer) documentSymbols(doc *lspDocument, symbols []*lox.Symbol) []map[string]any {
+	result := []map[string]any{}
+	for _, symbol := range symbols {
+		result = append(result, map[string]any{
+			"name":           symbol.Name,
+			"detail":         symbol.Detail,
+			"kind":           symbolKind(symbol.Kind),
+			"range":          s.spanToLSPRange(doc, symbol.Span),
+			"selectionRange": s.toLSPRange(doc, symbol.Location),
+			"children":       s.documentSymbols(doc, symbol.Children),
+		})
+	}
+	return result
+}
+
+func (s *lspServer) toLSPRange(doc *lspDocument, location lox.Location) lspRange {
+	return lspRange{
+		Start: s.position(doc, location.Line, location.Column),
+		End:   s.position(doc, location.Line, location.EndColumn),
+	}
+}
+
+func (s *lspServer) spanToLSPRange(doc *lspDocument, span lox.Span) lspRange {
+	return lspRange{
+		Start: s.position(doc, span.Start.Line, span.Start.Column),
+		End:   s.position(doc, span.End.Line, span.End.Column),
+	}
+}
+
+// the position in the protocol of the 1 based line and column of the tokens
+func (s *lspServer) position(doc *lspDocument, line int, column int) lspPosition {
+	character := column - 1
+	if !s.utf8 && line >= 1 && line <= len(doc.lines) {
+		text := doc.lines[line-1]
+		before := text[:min(character, len(text))]
+		character += utf16Length(before) - len(before)
+	}
+	return lspPosition{Line: line - 1, Character: character}
+}
+
+// the 1 based column of the tokens at the position in the protocol
+func (s *lspServer) column(doc *lspDocument, position lspPosition) int {
+	if s.utf8 || position.Line < 0 || position.Line >= len(doc.lines) {
+		return position.Character + 1
+	}
+	text := doc.lines[position.Line]
+	units := 0
+	for idx, r := range text {
+		if units >= position.Character {
+			return idx + 1
+		}
+		units += utf16RuneLen(r)
+	}
+	return len(text) + 1 + position.Character - units
+}
+
+// runes outside the basic multilingual plane are a surrogate pair
+func utf16RuneLen(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// bytes which aren't valid UTF-8 count as one unit, like the replacement character
+func utf16Length(text string) int {
+	length := 0
+	for _, r := range text {
+		length += utf16RuneLen(r)
+	}
+	return length
+}
+
+// numbers for the kinds from the protocol
+func symbolKind(kind lox.SymbolKind) int {
+	switch kind {
+	case lox.SymbolModule:
+		return 2
+	case lox.SymbolClass:
+		return 5
+	case lox.SymbolMethod:
+		return 6
+	case lox.SymbolFunction:
+		return 12
+	default:
+		return 13 // variable
+	}
+}
+
+func completionKind(kind lox.SymbolKind) int {
+	switch kind {
+	case lox.SymbolMethod:
+		return 2
+	case lox.SymbolFunction:
+		return 3
+	case lox.SymbolClass:
+		return 7
+	case lox.SymbolModule:
+		return 9
+	default:
+		return 6 // variable
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+const lspTestURI = "file:///program.lox"
+
+const lspTestProgram = `fun add(a, b) {
+  var sum = a + b;
+  return sum;
+}
+
+class Counter {
+  init() { this.count = 0; }
+  increment(by) {
+    this.count = this.count + by;
+    return this;
+  }
+}
+
+var total = add(1, 2);
+print len("abc") + total;
+`
+
+type lspClient struct {
+	protocolClient
+	id          int
+	diagnostics map[string][]any // latest published for each uri
+	encoding    string           // of the positions, as the server chose
+}
+
+func newLSPClient(t *testing.T) *lspClient {
+	return newLSPClientWith(t, map[string]any{})
+}
+
+// initializes the server with the capabilities of the client
+func newLSPClientWith(t *testing.T, capabilities map[string]any) *lspClient {
+	c := &lspClient{
+		protocolClient: newProtocolClient(t, serveLSP),
+		diagnostics:    make(map[string][]any),
+	}
+	result := c.request("initialize", map[string]any{"capabilities": capabilities}).(map[string]any)
+	c.encoding, _ = result["capabilities"].(map[string]any)["positionEncoding"].(string)
+	c.notify("initialized", map[string]any{})
+	return c
+}
+
+func (c *lspClient) send(message map[string]any) {
+	c.t.Helper()
+	message["jsonrpc"] = "2.0"
+	c.sendMessage(message)
+}
+
+func (c *lspClient) notify(method string, params any) {
+	c.t.Helper()
+	c.send(map[string]any{"method": method, "params": params})
+	if method == "textDocument/didOpen" || method == "textDocument/didChange" || method == "textDocument/didClose" {
+		c.receiveNotification()
+	}
+}
+
+func (c *lspClient) receiveNotification() {
+	c.t.Helper()
+	message := c.receive()
+	if message["method"] != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("expected diagnostics, got %v", message)
+	}
+	params := message["params"].(map[string]any)
+	c.diagnostics[params["uri"].(string)] = params["diagnostics"].([]any)
+}
+
+// sends the request and gives the response's result
+func (c *lspClient) request(method string, params any) any {
+	c.t.Helper()
+	c.id++
+	c.send(map[string]any{"id": c.id, "method": method, "params": params})
+	message := c.receive()
+	if message["id"] != float64(c.id) {
+		c.t.Fatalf("expected the response to %s, got %v", method, message)
+	}
+	if message["error"] != nil {
+		c.t.Fatalf("%s failed: %v", method, message["error"])
+	}
+	return message["result"]
+}
+
+func (c *lspClient) open(text string) {
+	c.t.Helper()
+	c.notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": lspTestURI, "languageId": "lox", "version": 1, "text": text},
+	})
+}
+
+// line and character are 0 based like in the protocol
+func (c *lspClient) atPosition(method string, line int, character int) any {
+	c.t.Helper()
+	return c.request(method, map[string]any{
+		"textDocument": map[string]any{"uri": lspTestURI},
+		"position":     map[string]any{"line": line, "character": character},
+		"context":      map[string]any{"includeDeclaration": true},
+	})
+}
+
+func (c *lspClient) shutdown() {
+	c.t.Helper()
+	c.request("shutdown", nil)
+	c.send(map[string]any{"method": "exit"})
+	c.waitServed()
+}
+
+// "line:character" of the start of a range
+func rangeStart(value any) string {
+	start := value.(map[string]any)["range"].(map[string]any)["start"].(map[string]any)
+	return fmt.Sprintf("%v:%v", start["line"], start["character"])
+}
+
+// the range as "line:character-line:character"
+func rangeString(value any) string {
+	r := value.(map[string]any)
+	start, end := r["start"].(map[string]any), r["end"].(map[string]any)
+	return fmt.Sprintf("%v:%v-%v:%v", start["line"], start["character"], end["line"], end["character"])
+}
+
+func TestLSPDiagnostics(t *testing.T) {
+	c := newLSPClient(t)
+	c.open("var a = 1\nprint a;\nfun f() {\n  var x = x;\n}\n")
+
+	var messages, starts []string
+	for _, diagnostic := range c.diagnostics[lspTestURI] {
+		messages = append(messages, diagnostic.(map[string]any)["message"].(string))
+		starts = append(starts, rangeStart(diagnostic))
+	}
+	expected := []string{
+		"Error at 'print': Expect ';' after expression.",
+		"Error at 'x': Can't read local variable in its own initializer.",
+	}
+	if !slices.Equal(messages, expected) || !slices.Equal(starts, []string{"1:0", "3:10"}) {
+		t.Errorf("unexpected diagnostics %v at %v", messages, starts)
+	}
+
+	c.notify("textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": lspTestURI, "version": 2},
+		"contentChanges": []any{map[string]any{"text": "var a = 1;\nprint a;\n"}},
+	})
+	if diagnostics := c.diagnostics[lspTestURI]; len(diagnostics) != 0 {
+		t.Errorf("expected no diagnostics after fixing the code, got %v", diagnostics)
+	}
+	c.shutdown()
+}
+
+// editors send the code on every keystroke, the server has to keep running for unfinished code
+func TestLSPIncompleteDocument(t *testing.T) {
+	c := newLSPClient(t)
+	c.open("var x = 1;\nprint x +")
+
+	diagnostics := c.diagnostics[lspTestURI]
+	if len(diagnostics) != 1 || diagnostics[0].(map[string]any)["message"] != "Error at end: Expect expression." {
+		t.Errorf("expected the missing operand to be reported, got %v", diagnostics)
+	}
+	if hover := c.atPosition("textDocument/hover", 0, 4); hover == nil { // the x which was declared
+		t.Errorf("expected the server to still answer requests")
+	}
+	c.shutdown()
+}
+
+func TestLSPDefinitionAndReferences(t *testing.T) {
+	c := newLSPClient(t)
+	c.open(lspTestProgram)
+
+	definition := c.atPosition("textDocument/definition", 13, 13) // add in add(1, 2)
+	if got := rangeStart(definition); got != "0:4" {
+		t.Errorf("expected add to be defined at 0:4, got %s", got)
+	}
+	definition = c.atPosition("textDocument/definition", 2, 10) // sum in return sum
+	if got := rangeStart(definition); got != "1:6" {
+		t.Errorf("expected sum to be defined at 1:6, got %s", got)
+	}
+	if definition := c.atPosition("textDocument/definition", 14, 7); definition != nil {
+		t.Errorf("expected no definition for a native function, got %v", definition)
+	}
+
+	var starts []string
+	for _, location := range c.atPosition("textDocument/references", 0, 8).([]any) { // the parameter a
+		starts = append(starts, rangeStart(location))
+	}
+	if !slices.Equal(starts, []string{"0:8", "1:12"}) {
+		t.Errorf("unexpected references to a %v", starts)
+	}
+	starts = nil
+	for _, location := range c.atPosition("textDocument/references", 13, 5).([]any) { // the global total
+		starts = append(starts, rangeStart(location))
+	}
+	if !slices.Equal(starts, []string{"13:4", "14:19"}) {
+		t.Errorf("unexpected references to total %v", starts)
+	}
+	c.shutdown()
+}
+
+// "😀" is 4 bytes and 2 UTF-16 code units, so the positions after it depend on the encoding
+func TestLSPPositionEncoding(t *testing.T) {
+	const code = "var e = \"😀\"; print e;\n"
+	cases := []struct {
+		offered  []string
+		encoding string
+		usage    int // character of the second e
+	}{
+		{nil, "utf-16", 20},
+		{[]string{"utf-16"}, "utf-16", 20},
+		{[]string{"utf-8", "utf-16"}, "utf-8", 22},
+	}
+	for _, test := range cases {
+		capabilities := map[string]any{}
+		if test.offered != nil {
+			capabilities["general"] = map[string]any{"positionEncodings": test.offered}
+		}
+		c := newLSPClientWith(t, capabilities)
+		if c.encoding != test.encoding {
+			t.Errorf("offered %v, expected the encoding %s, got %q", test.offered, test.encoding, c.encoding)
+		}
+		c.open(code)
+
+		var ranges []string
+		for _, location := range c.atPosition("textDocument/references", 0, test.usage).([]any) {
+			ranges = append(ranges, rangeString(location.(map[string]any)["range"]))
+		}
+		expected := []string{"0:4-0:5", fmt.Sprintf("0:%d-0:%d", test.usage, test.usage+1)}
+		if !slices.Equal(ranges, expected) {
+			t.Errorf("%s: expected the references %v, got %v", test.encoding, expected, ranges)
+		}
+		c.shutdown()
+	}
+}
+
+func TestLSPHoverAndSymbols(t *testing.T) {
+	c := newLSPClient(t)
+	c.open(lspTestProgram)
+
+	hovers := map[[2]int]string{
+		{13, 13}: "fun add(a, b)",
+		{14, 7}:  "native fun len, takes 1 argument",
+		{7, 4}:   "fun Counter.increment(by)",
+		{5, 8}:   "class Counter",
+	}
+	for position, expected := range hovers {
+		hover := c.atPosition("textDocument/hover", position[0], position[1])
+		value := hover.(map[string]any)["contents"].(map[string]any)["value"]
+		if value != "```lox\n"+expected+"\n```" {
+			t.Errorf("expected the hover at %v to be %s, got %v", position, expected, value)
+		}
+	}
+	if hover := c.atPosition("textDocument/hover", 4, 0); hover != nil {
+		t.Errorf("expected no hover on an empty line, got %v", hover)
+	}
+
+	symbols := c.request("textDocument/documentSymbol", map[string]any{
+		"textDocument": map[string]any{"uri": lspTestURI},
+	}).([]any)
+	var outline []string
+	var walk func(symbols []any, prefix string)
+	walk = func(symbols []any, prefix string) {
+		for _, symbol := range symbols {
+			symbol := symbol.(map[string]any)
+			outline = append(outline, fmt.Sprintf("%s%s/%v %s %s", prefix, symbol["name"], symbol["kind"],
+				rangeString(symbol["range"]), rangeString(symbol["selectionRange"])))
+			walk(symbol["children"].([]any), prefix+"  ")
+		}
+	}
+	walk(symbols, "")
+	// the range is of the whole declaration, the selection range of the name
+	expected := []string{
+		"add/12 0:0-3:1 0:4-0:7",
+		"Counter/5 5:0-11:1 5:6-5:13",
+		"  init/6 6:2-6:28 6:2-6:6",
+		"  increment/6 7:2-10:3 7:2-7:11",
+	}
+	if !slices.Equal(outline, expected) {
+		t.Errorf("unexpected outline %v", outline)
+	}
+	c.shutdown()
+}
+
+func TestLSPCompletion(t *testing.T) {
+	c := newLSPClient(t)
+	c.open(lspTestProgram)
+
+	labels := func(line int, character int) []string {
+		var labels []string
+		for _, item := range c.atPosition("textDocument/completion", line, character).([]any) {
+			labels = append(labels, item.(map[string]any)["label"].(string))
+		}
+		return labels
+	}
+
+	inFunction := labels(2, 9) // before sum in return sum
+	if !slices.Equal(inFunction[:6], []string{"sum", "b", "a", "add", "Counter", "total"}) {
+		t.Errorf("expected the locals then the globals, got %v", inFunction[:6])
+	}
+	if !slices.Contains(inFunction, "len") || !slices.Contains(inFunction, "time") {
+		t.Errorf("expected the natives to be completed, got %v", inFunction)
+	}
+	if outside := labels(14, 0); slices.Contains(outside, "sum") || slices.Contains(outside, "by") {
+		t.Errorf("expected no locals outside their scope, got %v", outside)
+	}
+
+	c.notify("textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": lspTestURI, "version": 2},
+		"contentChanges": []any{map[string]any{"text": lspTestProgram + "Counter().inc"}},
+	})
+	if methods := labels(15, 13); !slices.Equal(methods, []string{"increment"}) {
+		t.Errorf("expected the methods after a dot, got %v", methods)
+	}
+	c.shutdown()
+}
@@ -1,40 +1,88 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"golox/lox"
 )
 
+// shared by all the input functions and the debugger, so nothing buffered by one is lost for the other
+var stdin = bufio.NewReader(os.Stdin)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: ./your_program.sh <command> [--seed=<n>] [--warnings] [--diagnostics=json] [--snippets=<when>] [--check] [--enable=<rules>] [--disable=<rules>] [--keep-for] <filename> [args...]")
+	fmt.Fprintln(os.Stderr, "Commands available: tokenize, parse, evaluate, visualize, run, debug, fmt, lint, dap, lsp")
+	os.Exit(1)
+}
+
 func main() {
-	if len(os.Args) < 3 {
-		fmt.Fprintln(os.Stderr, "Usage: ./your_program.sh <command> <filename>")
-		fmt.Fprintln(os.Stderr, "Commands available: tokenize, parse, evaluate, visualize, run")
-		os.Exit(1)
+	if len(os.Args) < 2 {
+		usage()
 	}
 
 	command := os.Args[1]
+	if command == "dap" { // the program to debug comes in the launch request
+		if err := serveDAP(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error serving DAP: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if command == "lsp" { // the files come from the editor
+		if err := serveLSP(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error serving LSP: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	filename := os.Args[2]
+	// flags go between the command and the filename, everything after the filename is for the script
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	flags.Usage = usage
+	seed := flags.Uint64("seed", 0, "seed for random numbers, also makes time virtual so runs are reproducible")
+	warnings := flags.Bool("warnings", false, "print warnings like unused variables found before running, to stderr")
+	diagnostics := flags.String("diagnostics", "text", "format of the errors and warnings, text or json with an object per line")
+	snippets := flags.String("snippets", "auto", "show the source line of errors and warnings, always, never or auto when stderr is a terminal")
+	check := flags.Bool("check", false, "with fmt, only check if the file is formatted without changing it")
+	enable := flags.String("enable", "", "with lint, comma separated rules to check instead of all of them")
+	disable := flags.String("disable", "", "with lint, comma separated rules not to check")
+	keepFor := flags.Bool("keep-for", false, "with parse, print for loops as they're written rather than desugared to while loops")
+	flags.Parse(os.Args[2:])
+	if flags.NArg() < 1 {
+		usage()
+	}
+
+	filename := flags.Arg(0)
+	if *diagnostics != "text" && *diagnostics != "json" {
+		fmt.Fprintf(os.Stderr, "Unknown diagnostics format '%s', it can be text or json.\n", *diagnostics)
+		os.Exit(1)
+	}
 	fileContents, err := os.ReadFile(filename)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
 		os.Exit(1)
 	}
 
-	lox.SetLogger(lox.Logger{
+	logger := lox.Logger{
 		Input: func(prompt string) (string, error) {
 			fmt.Print(prompt)
-			var input string
-			_, err := fmt.Scanln(&input)
-			if err != nil {
-				return "", err
-			}
-			return input, nil
+			return readLine(stdin)
+		},
+		ReadLine: func() (string, error) {
+			return readLine(stdin)
+		},
+		ReadAll: func() (string, error) {
+			data, err := io.ReadAll(stdin)
+			return string(data), err
 		},
 		Print: func(s string) {
 			fmt.Println(s)
@@ -49,12 +97,36 @@ func main() {
 			fmt.Fprintf(os.Stderr, "%s\n", msg)
 			fmt.Fprintf(os.Stderr, "[line %d:%d] %s\n", token.Line, token.Col, msg)
 		},
+	}
+	// off by default, so the output of programs which run fine stays the same
+	if *warnings {
+		logger.Warning = func(token lox.TokenLogMeta, severity lox.Severity, code string, msg string) {
+			fmt.Fprintf(os.Stderr, "[line %d:%d] %s: %s (%s)\n", token.Line, token.Col, severity, msg, code)
+		}
+	}
+	var jsonWriter *diagnosticWriter // nil for text
+	if *diagnostics == "json" {
+		jsonWriter = newDiagnosticWriter(filename, os.Stderr)
+		logger = jsonWriter.logger(logger)
+	} else if showSnippets(*snippets) {
+		logger = withSnippets(logger, fileContents)
+	}
+	lox.SetLogger(logger)
+
+	lox.SetFileSystem(lox.NewOSFileSystem())
+	lox.SetScriptArgs(flags.Args()[1:])
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			lox.SetDeterministic(*seed)
+		}
 	})
 
 	if command == "tokenize" {
 		lox.PrintTokens(fileContents)
 	} else if command == "parse" {
-		lox.Parse(fileContents)
+		output, exitCode := lox.Parse(fileContents, *keepFor)
+		fmt.Print(output)
+		os.Exit(exitCode)
 	} else if command == "evaluate" {
 		lox.Evaluate(fileContents)
 	} else if command == "visualize" {
@@ -65,8 +137,109 @@ func main() {
 		defer stop()
 		exitCode := lox.Run(fileContents, ctx)
 		os.Exit(exitCode)
+	} else if command == "fmt" {
+		os.Exit(formatFile(filename, fileContents, *check))
+	} else if command == "lint" {
+		os.Exit(lintFile(fileContents, *enable, *disable, jsonWriter))
+	} else if command == "debug" {
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		exitCode := debugFile(fileContents, ctx)
+		os.Exit(exitCode)
 	} else {
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
 		os.Exit(1)
 	}
 }
+
+/*
+rewrites the file in the canonical style. With check, the file isn't changed and
+the exit code is 1 if it isn't formatted.
+*/
+func formatFile(filename string, code []byte, check bool) int {
+	formatted, exitCode := lox.Format(code)
+	if exitCode != 0 {
+		return exitCode
+	}
+	if bytes.Equal(formatted, code) {
+		return 0
+	}
+	if check {
+		fmt.Fprintf(os.Stderr, "%s is not formatted\n", filename)
+		return 1
+	}
+	info, err := os.Stat(filename)
+	if err == nil {
+		err = os.WriteFile(filename, formatted, info.Mode())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+/*
+prints the warnings for the program, and the exit code is 1 if there are any. The
+rules to check are all of them, or only the enabled ones, except the disabled ones.
+The warnings are printed as json with a jsonWriter.
+*/
+func lintFile(code []byte, enable string, disable string, jsonWriter *diagnosticWriter) int {
+	disabled := make(map[string]bool)
+	known := make(map[string]bool)
+	for _, rule := range lox.LintRules {
+		known[rule.Name] = true
+		disabled[rule.Name] = enable != ""
+	}
+	for _, list := range []struct {
+		rules     string
+		isEnabled bool
+	}{{enable, true}, {disable, false}} {
+		for _, rule := range strings.Split(list.rules, ",") {
+			rule = strings.TrimSpace(rule)
+			if rule == "" {
+				continue
+			}
+			if !known[rule] {
+				fmt.Fprintf(os.Stderr, "Unknown lint rule '%s'. The rules are:\n", rule)
+				for _, rule := range lox.LintRules {
+					fmt.Fprintf(os.Stderr, "  %-22s %s\n", rule.Name, rule.Description)
+				}
+				return 1
+			}
+			disabled[rule] = !list.isEnabled
+		}
+	}
+
+	warnings, exitCode := lox.Lint(code, disabled)
+	if exitCode != 0 {
+		return exitCode
+	}
+	if jsonWriter != nil {
+		jsonWriter = newDiagnosticWriter(jsonWriter.filename, os.Stdout) // like the text
+	}
+	for _, warning := range warnings {
+		if jsonWriter != nil {
+			jsonWriter.lintWarning(warning)
+			continue
+		}
+		fmt.Printf("[line %d:%d] Warning: %s (%s)\n", warning.Location.Line, warning.Location.Column, warning.Message, warning.Rule)
+	}
+	if len(warnings) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// reads till the newline, the last line of the input may not have one
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r"), nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golox/lox"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name, code, expected string
+		keepFor              bool
+	}{
+		{
+			"lone expression",
+			"(5 - (3 - 1)) + -1",
+			"(+ (group (- 5.0 (group (- 3.0 1.0)))) (- 1.0))\n",
+			false,
+		},
+		{
+			"declarations",
+			"var a = 1;\nvar b;\nfun add(x, y) { return x + y; }\nclass B < A { init() { this.x = 1; } m() { return; } }\n",
+			"(var a = 1.0)\n(var b)\n(fun add(x y) (return (+ x y)))\n(class B < A (fun init() (; (= (this) x 1.0))) (fun m() (return)))\n",
+			false,
+		},
+		{
+			"control flow",
+			"if (a) print a; else { print -a; }\nif (b) b();\nwhile (a < 3) a++;\n",
+			"(if-else a (print a) (block (print (- a))))\n(if b (; (call b)))\n(while (< a 3.0) (; (post++ a 1.0)))\n",
+			false,
+		},
+		{
+			"desugared for loops",
+			"for (var i = 0; i < 2; i = i + 1) print i;\nfor (;;) {}\n",
+			"(block (var i = 0.0) (while (< i 2.0) (block (print i) (; (= i (+ i 1.0))))))\n(while true (block))\n",
+			false,
+		},
+		{
+			"for loops as they're written",
+			"for (var i = 0; i < 2; i = i + 1) print i;\nfor (;;) {}\n",
+			"(for (var i = 0.0) (< i 2.0) (= i (+ i 1.0)) (print i))\n(for nil nil nil (block))\n",
+			true,
+		},
+	}
+	for _, c := range cases {
+		output, exitCode := lox.Parse([]byte(c.code), c.keepFor)
+		if exitCode != 0 || output != c.expected {
+			t.Errorf("%s: expected\n%s\ngot exit code %d and\n%s", c.name, c.expected, exitCode, output)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	var errors []string
+	lox.SetLogger(lox.Logger{
+		ParseError: func(token lox.TokenLogMeta, msg string) {
+			errors = append(errors, msg)
+		},
+	})
+	defer lox.SetLogger(lox.Logger{})
+	lox.ResetErrorState()
+	defer lox.ResetErrorState()
+
+	cases := map[string]string{
+		"print 1":               "Error at end: Expect ';' after expression.",
+		"var x = 1;\nprint x +": "Error at end: Expect expression.",
+	}
+	for code, expected := range cases {
+		errors = nil
+		lox.ResetErrorState()
+		output, exitCode := lox.Parse([]byte(code), false)
+		if exitCode != 65 || output != "" || len(errors) != 1 || errors[0] != expected {
+			t.Errorf("%q: expected %q to be reported once, got exit code %d, %q and the errors %v", code, expected, exitCode, output, errors)
+		}
+	}
+}
+
+// every lox test which compiles can be parsed, the formatter parses them the same way
+func TestParseTests(t *testing.T) {
+	lox.SetLogger(lox.Logger{
+		ScanError:  func(int, int, string) {},
+		ParseError: func(lox.TokenLogMeta, string) {},
+	})
+	defer lox.SetLogger(lox.Logger{})
+	err := filepath.WalkDir("../../test", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".lox" {
+			return err
+		}
+		code, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		lox.ResetErrorState()
+		if _, exitCode := lox.Format(code); exitCode != 0 {
+			return nil
+		}
+		lox.ResetErrorState()
+		if _, exitCode := lox.Parse(code, false); exitCode != 0 {
+			t.Errorf("%s: expected to be parsed, got exit code %d", path, exitCode)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lox.ResetErrorState()
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+)
+
+/*
+plays the editor's side of the debug adapter or language server protocol against a
+server running on pipes, the messages are framed with readMessage and writeMessage
+like the server does.
+*/
+type protocolClient struct {
+	t      *testing.T
+	writer io.WriteCloser
+	reader *bufio.Reader
+	served chan error
+}
+
+func newProtocolClient(t *testing.T, serve func(in io.Reader, out io.Writer) error) protocolClient {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+	c := protocolClient{
+		t:      t,
+		writer: clientWriter,
+		reader: bufio.NewReader(clientReader),
+		served: make(chan error, 1),
+	}
+	go func() {
+		c.served <- serve(serverReader, serverWriter)
+		serverWriter.Close()
+	}()
+	return c
+}
+
+func (c *protocolClient) sendMessage(message map[string]any) {
+	c.t.Helper()
+	content, _ := json.Marshal(message)
+	if err := writeMessage(c.writer, content); err != nil {
+		c.t.Fatalf("sending %v: %v", message, err)
+	}
+}
+
+// fails the test if the server closes the connection or doesn't answer in time
+func (c *protocolClient) receive() map[string]any {
+	c.t.Helper()
+	received := make(chan map[string]any, 1)
+	go func() {
+		content, err := readMessage(c.reader)
+		if err != nil {
+			received <- nil
+			return
+		}
+		var message map[string]any
+		json.Unmarshal(content, &message)
+		received <- message
+	}()
+	select {
+	case message := <-received:
+		if message == nil {
+			c.t.Fatal("server closed the connection")
+		}
+		return message
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timed out waiting for a message")
+		return nil
+	}
+}
+
+// waits for the server to stop after the client asked it to
+func (c *protocolClient) waitServed() {
+	c.t.Helper()
+	if err := <-c.served; err != nil {
+		c.t.Fatalf("server failed: %v", err)
+	}
+}
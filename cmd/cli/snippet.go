@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"golox/lox"
+)
+
+/*
+the source line of a diagnostic with its span underlined, and the suggestion after
+it like "did you mean 'count'?". Columns are of bytes and the end column is just
+after the span, it's "" if the line isn't in the source.
+
+	3 | print cout + 1;
+	  |       ^^^^ did you mean 'count'?
+*/
+func renderSnippet(source []string, line int, startCol int, endCol int, suggestion string) string {
+	if line < 1 || line > len(source) {
+		return ""
+	}
+	text := strings.TrimRight(source[line-1], "\r")
+	startCol = min(max(startCol, 1), len(text)+1)
+	endCol = min(max(endCol, startCol+1), len(text)+2) // at least one caret, which can be at the end of the line
+
+	// the underline keeps the tabs of the line, and a space for each other character, so it lines up
+	var padding strings.Builder
+	for _, char := range text[:startCol-1] {
+		if char == '\t' {
+			padding.WriteRune('\t')
+		} else {
+			padding.WriteRune(' ')
+		}
+	}
+	underline := strings.Repeat("^", max(1, len([]rune(text[startCol-1:min(endCol-1, len(text))]))))
+	if suggestion != "" {
+		underline += fmt.Sprintf(" did you mean '%s'?", suggestion)
+	}
+
+	gutter := strings.Repeat(" ", len(fmt.Sprint(line)))
+	return fmt.Sprintf("  %d | %s\n  %s | %s%s\n", line, text, gutter, padding.String(), underline)
+}
+
+/*
+snippets are shown with "always", or with "auto" when stderr is a terminal. Output
+which is piped, like to the tests, stays the same.
+*/
+func showSnippets(mode string) bool {
+	if mode != "auto" {
+		return mode == "always"
+	}
+	info, err := os.Stderr.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// wraps the error and warning callbacks of the logger to print the snippet after each one
+func withSnippets(logger lox.Logger, code []byte) lox.Logger {
+	source := strings.Split(string(code), "\n")
+	show := func(token lox.TokenLogMeta) {
+		line, endCol := token.Line, token.Col
+		if token.StartLine > 0 && token.StartLine < token.Line {
+			// a multiline string is underlined till the end of its first line
+			line, endCol = token.StartLine, math.MaxInt
+		}
+		fmt.Fprint(os.Stderr, renderSnippet(source, line, token.StartCol, endCol, token.Suggestion))
+	}
+
+	scanError, parseError, runtimeError, warning := logger.ScanError, logger.ParseError, logger.RuntimeError, logger.Warning
+	logger.ScanError = func(line int, col int, msg string) {
+		scanError(line, col, msg)
+		// the column is just after the character with the error
+		show(lox.TokenLogMeta{Line: line, Col: col, StartCol: col - 1})
+	}
+	logger.ParseError = func(token lox.TokenLogMeta, msg string) {
+		parseError(token, msg)
+		show(token)
+	}
+	logger.RuntimeError = func(token lox.TokenLogMeta, msg string) {
+		runtimeError(token, msg)
+		show(token)
+	}
+	if warning != nil {
+		logger.Warning = func(token lox.TokenLogMeta, severity lox.Severity, code string, msg string) {
+			warning(token, severity, code, msg)
+			show(token)
+		}
+	}
+	return logger
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"golox/lox"
+)
+
+func TestRenderSnippet(t *testing.T) {
+	source := []string{"var count = 1;", "\tprint cout + \"é\" + x;"}
+	cases := []struct {
+		line, startCol, endCol int
+		suggestion, expected   string
+	}{
+		{2, 8, 12, "count", "  2 | \tprint cout + \"é\" + x;\n    | \t      ^^^^ did you mean 'count'?\n"},
+		{2, 22, 23, "", "  2 | \tprint cout + \"é\" + x;\n    | \t" + strings.Repeat(" ", 19) + "^\n"}, // after the 2 byte é
+		{1, 15, 15, "", "  1 | var count = 1;\n    |               ^\n"},                               // the end of the line
+		{3, 1, 1, "", ""},
+	}
+	for _, c := range cases {
+		if got := renderSnippet(source, c.line, c.startCol, c.endCol, c.suggestion); got != c.expected {
+			t.Errorf("expected the snippet for %d:%d\n%q\ngot\n%q", c.line, c.startCol, c.expected, got)
+		}
+	}
+}
+
+func TestUndefinedNameSuggestions(t *testing.T) {
+	cases := map[string]string{
+		"var count = 1;\nprint cout;":                                            "count",
+		"fun f() { var total = 1; print totl; }\nf();":                           "total",
+		"class A { length() {} }\nA().lenght();":                                 "length",
+		"class A { init() { this.width = 1; } }\nprint A().widht;":               "width",
+		"class A { size() {} }\nclass B < A { m() { super.szie(); } }\nB().m();": "size",
+		"print lne;":                 "len",
+		"var count = 1;\nprint xyz;": "",
+	}
+	for code, expected := range cases {
+		var suggestion string
+		lox.SetLogger(lox.Logger{
+			Print: func(string) {},
+			RuntimeError: func(token lox.TokenLogMeta, msg string) {
+				suggestion = token.Suggestion
+			},
+		})
+		lox.ResetErrorState()
+		lox.Run([]byte(code), context.Background())
+		if suggestion != expected {
+			t.Errorf("expected the suggestion %q for %q, got %q", expected, code, suggestion)
+		}
+	}
+	lox.SetLogger(lox.Logger{})
+	lox.ResetErrorState()
+}
+
+// the snippet follows the usual error lines, so tools reading them still work
+func TestWithSnippets(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = writer
+	defer func() { os.Stderr = stderr }()
+
+	var messages []string
+	lox.SetLogger(withSnippets(lox.Logger{
+		RuntimeError: func(token lox.TokenLogMeta, msg string) {
+			messages = append(messages, msg)
+		},
+	}, []byte("var count = 1;\nprint cout;\n")))
+	lox.ResetErrorState()
+	lox.Run([]byte("var count = 1;\nprint cout;\n"), context.Background())
+	lox.SetLogger(lox.Logger{})
+	lox.ResetErrorState()
+	writer.Close()
+
+	var out bytes.Buffer
+	out.ReadFrom(reader)
+	expected := "  2 | print cout;\n    |       ^^^^ did you mean 'count'?\n"
+	if len(messages) != 1 || out.String() != expected {
+		t.Errorf("expected the error and then the snippet, got %v and\n%s", messages, out.String())
+	}
+	if !strings.HasPrefix(messages[0], "Undefined variable 'cout'.") {
+		t.Errorf("expected the message to stay the same, got %q", messages[0])
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"golox/lox"
+)
+
+func TestWarnings(t *testing.T) {
+	var warnings, output []string
+	lox.SetLogger(lox.Logger{
+		Print: func(s string) {
+			output = append(output, s)
+		},
+		Warning: func(token lox.TokenLogMeta, severity lox.Severity, code string, msg string) {
+			warnings = append(warnings, fmt.Sprintf("%d:%d %s %s", token.Line, token.StartCol, severity, code))
+		},
+	})
+	defer lox.SetLogger(lox.Logger{})
+	lox.ResetErrorState()
+
+	code := `var global = 1;
+fun f(param) {
+  var unused = 1;
+  var _ignored = 2;
+  var written = 0;
+  written = 3;
+  var read = 4;
+  print "a" + nil;
+  print 2 + "b";
+  print read + "c";
+}
+f(1);
+`
+	if exitCode := lox.Run([]byte(code), context.Background()); exitCode != 0 {
+		t.Fatalf("expected the program to run despite the warnings, got exit code %d", exitCode)
+	}
+	expected := []string{
+		"8:13 Warning string-coercion",
+		"9:11 Info string-coercion",
+		"3:7 Warning unused-local",
+		"5:7 Warning unused-local",
+	}
+	if !slices.Equal(warnings, expected) {
+		t.Errorf("expected the warnings %v, got %v", expected, warnings)
+	}
+	if !slices.Equal(output, []string{"anil", "2b", "4c"}) {
+		t.Errorf("unexpected output %v", output)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"context"
 	"fmt"
 	"golox/lox"
+	"io"
+	"strings"
 	"sync"
 	"syscall/js"
 )
@@ -20,6 +22,10 @@ var state runnerState
 func main() {
 	c := make(chan struct{}, 0)
 
+	// there is no disk in the browser, files written by lox programs are kept in memory
+	// and are available across runs till the page is reloaded
+	lox.SetFileSystem(lox.NewMemoryFileSystem())
+
 	js.Global().Set("loxrun", functionRunner(runLoxCode))
 
 	// this only works right now where is a sleep in the program, where
@@ -74,11 +80,20 @@ func runLoxCode(this js.Value, args []js.Value) {
 	state.cancelRun = cancel
 
 	lox.SetLogger(lox.Logger{
-		Input: func(prompt string) (string, error) {
-			// we'll take input through prompt  in js to keep things simple
-			inputPromise := js.Global().Get("promptInput").Invoke(prompt)
-			result, _ := await(inputPromise)
-			return result[0].String(), nil
+		Input: promptLine,
+		ReadLine: func() (string, error) {
+			return promptLine("")
+		},
+		ReadAll: func() (string, error) {
+			// there is no stream to read from, so lines are asked for till the prompt is cancelled
+			var lines []string
+			for {
+				line, err := promptLine("")
+				if err == io.EOF {
+					return strings.Join(lines, "\n"), nil
+				}
+				lines = append(lines, line)
+			}
 		},
 		Print: func(s string) {
 			logOutput(s, false)
@@ -92,6 +107,9 @@ func runLoxCode(this js.Value, args []js.Value) {
 		RuntimeError: func(token lox.TokenLogMeta, msg string) {
 			logOutput(fmt.Sprintf("[line %d:%d] %s", token.Line, token.Col, msg), true)
 		},
+		Warning: func(token lox.TokenLogMeta, severity lox.Severity, code string, msg string) {
+			logToJs(callbackJs, "warning", fmt.Sprintf("[line %d:%d] %s: %s (%s)", token.Line, token.Col, severity, msg, code))
+		},
 	})
 
 	lox.ResetErrorState()
@@ -103,6 +121,16 @@ func runLoxCode(this js.Value, args []js.Value) {
 	}
 }
 
+// we'll take input through prompt in js to keep things simple, cancelling it is the end of input
+func promptLine(prompt string) (string, error) {
+	inputPromise := js.Global().Get("promptInput").Invoke(prompt)
+	result, _ := await(inputPromise)
+	if len(result) == 0 || result[0].IsNull() || result[0].IsUndefined() {
+		return "", io.EOF
+	}
+	return result[0].String(), nil
+}
+
 func logToJs(callbackJs js.Value, kind string, msg string) {
 	data := make(map[string]interface{})
 	data["type"] = kind
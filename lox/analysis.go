@@ -0,0 +1,408 @@
+package lox
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+/*
+Analyze checks a program without running it, for editor tooling like the language
+server. It gives the errors the scanner, parser and resolver find, and what the
+resolver knows about the names in the program - where each one is declared and
+used. The resolver runs even when there are parse errors, on the statements which
+could be parsed, so editors still work while the code is being typed.
+*/
+type Analysis struct {
+	Diagnostics []Diagnostic
+	Symbols     []*Symbol // functions and classes in the order they're declared, nested ones as Children
+
+	lines        []string
+	declarations []*declaration
+	globals      map[string]*declaration // first declaration of each global name
+	references   []reference
+	natives      map[string]any
+}
+
+// a span on a single line, columns start at 1 and EndColumn is just after the span
+type Location struct {
+	Line      int
+	Column    int
+	EndColumn int
+}
+
+type Diagnostic struct {
+	Location Location
+	Message  string
+}
+
+type SymbolKind int
+
+const (
+	SymbolVariable SymbolKind = iota
+	SymbolParameter
+	SymbolFunction
+	SymbolClass
+	SymbolMethod
+	SymbolModule // native modules like time
+)
+
+type Symbol struct {
+	Name     string
+	Kind     SymbolKind
+	Detail   string   // how it's declared, like "fun add(a, b)" or "class B < A"
+	Location Location // of the name where it's declared
+	Span     Span     // of the whole declaration, like a function from "fun" till its "}"
+	Children []*Symbol
+}
+
+type Completion struct {
+	Label  string
+	Kind   SymbolKind
+	Detail string
+}
+
+type declaration struct {
+	symbol     *Symbol
+	scopeEnd   token // last token of the scope it's declared in, zero for globals and methods
+	references []Location
+}
+
+type reference struct {
+	location Location
+	name     string
+	decl     *declaration // nil for natives and undefined names
+}
+
+func Analyze(code []byte) *Analysis {
+	interpreter := newInterpreter()
+	analysis := &Analysis{
+		lines:   strings.Split(string(code), "\n"),
+		globals: make(map[string]*declaration),
+		natives: interpreter.globals.vars,
+	}
+
+	addDiagnostic := func(loc Location, msg string) {
+		analysis.Diagnostics = append(analysis.Diagnostics, Diagnostic{Location: loc, Message: msg})
+	}
+	withLogger(Logger{
+		ScanError: func(line int, col int, msg string) {
+			addDiagnostic(Location{Line: line, Column: max(col-1, 1), EndColumn: max(col, 2)}, msg)
+		},
+		ParseError: func(token TokenLogMeta, msg string) {
+			column := token.StartCol
+			if token.StartLine < token.Line {
+				column = 1 // the last line of a multiline string
+			}
+			addDiagnostic(Location{Line: token.Line, Column: column, EndColumn: token.Col}, msg)
+		},
+	}, func() {
+		// the code is being typed, so a bug in handling some unfinished code mustn't stop the editor's server
+		defer func() {
+			if r := recover(); r != nil {
+				addDiagnostic(Location{Line: 1, Column: 1, EndColumn: 1}, fmt.Sprintf("Internal error: %v", r))
+			}
+		}()
+		tokens := tokenize(code)
+		statements := newParser[expr](tokens).parse()
+		symbols := &symbolTable{analysis: analysis}
+		resolver := newResolver(interpreter)
+		resolver.symbols = symbols
+		resolver.resolve(statements)
+		symbols.resolveGlobals()
+	})
+	return analysis
+}
+
+// the declaration of the name at the position, which can be where it's declared or used
+func (a *Analysis) DefinitionAt(line int, column int) (Location, bool) {
+	decl, _ := a.declarationAt(line, column)
+	if decl == nil {
+		return Location{}, false
+	}
+	return decl.symbol.Location, true
+}
+
+// all the uses of the name at the position, in the order they're in the code
+func (a *Analysis) ReferencesAt(line int, column int, includeDeclaration bool) []Location {
+	decl, _ := a.declarationAt(line, column)
+	if decl == nil {
+		return nil
+	}
+	var locations []Location
+	if includeDeclaration {
+		locations = append(locations, decl.symbol.Location)
+	}
+	locations = append(locations, decl.references...)
+	slices.SortFunc(locations, compareLocations)
+	return locations
+}
+
+// how the name at the position is declared, like the signature of a function
+func (a *Analysis) HoverAt(line int, column int) (string, Location, bool) {
+	decl, ref := a.declarationAt(line, column)
+	if decl != nil {
+		if ref != nil {
+			return decl.symbol.Detail, ref.location, true
+		}
+		return decl.symbol.Detail, decl.symbol.Location, true
+	}
+	if ref != nil {
+		if native, ok := a.natives[ref.name]; ok {
+			return nativeSymbol(ref.name, native).Detail, ref.location, true
+		}
+	}
+	return "", Location{}, false
+}
+
+/*
+names which can be typed at the position. After a "." they're the methods of the
+classes in the program, as the type of the object isn't known. Otherwise, they're
+the variables in scope, inner ones first, then the globals and the natives.
+*/
+func (a *Analysis) Completions(line int, column int) []Completion {
+	var completions []Completion
+	seen := make(map[string]bool)
+	add := func(symbol *Symbol) {
+		if seen[symbol.Name] {
+			return
+		}
+		seen[symbol.Name] = true
+		completions = append(completions, Completion{Label: symbol.Name, Kind: symbol.Kind, Detail: symbol.Detail})
+	}
+
+	if a.isAfterDot(line, column) {
+		for _, decl := range a.declarations {
+			if decl.symbol.Kind == SymbolMethod && decl.symbol.Name != "init" {
+				add(decl.symbol)
+			}
+		}
+		return completions
+	}
+
+	cursor := Location{Line: line, Column: column, EndColumn: column}
+	var locals []*declaration
+	for _, decl := range a.declarations {
+		if decl.scopeEnd.line == 0 {
+			continue
+		}
+		scopeEnd := Location{Line: decl.scopeEnd.line, Column: decl.scopeEnd.startColumn()}
+		if compareLocations(decl.symbol.Location, cursor) < 0 && compareLocations(cursor, scopeEnd) <= 0 {
+			locals = append(locals, decl)
+		}
+	}
+	// the innermost scopes are declared last
+	for idx := len(locals) - 1; idx >= 0; idx-- {
+		add(locals[idx].symbol)
+	}
+	for _, decl := range a.declarations {
+		if a.globals[decl.symbol.Name] == decl {
+			add(decl.symbol)
+		}
+	}
+	natives := make([]string, 0, len(a.natives))
+	for name := range a.natives {
+		natives = append(natives, name)
+	}
+	slices.Sort(natives)
+	for _, name := range natives {
+		add(nativeSymbol(name, a.natives[name]))
+	}
+	return completions
+}
+
+// the declaration the name at the position refers to, and the reference if it's a use of the name
+func (a *Analysis) declarationAt(line int, column int) (*declaration, *reference) {
+	for idx := range a.references {
+		if a.references[idx].location.contains(line, column) {
+			return a.references[idx].decl, &a.references[idx]
+		}
+	}
+	for _, decl := range a.declarations {
+		if decl.symbol.Location.contains(line, column) {
+			return decl, nil
+		}
+	}
+	return nil, nil
+}
+
+// the cursor can be just after the name too
+func (l Location) contains(line int, column int) bool {
+	return l.Line == line && l.Column <= column && column <= l.EndColumn
+}
+
+func compareLocations(a, b Location) int {
+	return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+}
+
+// skips back over the name being typed to check for a "." before it
+func (a *Analysis) isAfterDot(line int, column int) bool {
+	if line < 1 || line > len(a.lines) {
+		return false
+	}
+	text := a.lines[line-1]
+	idx := min(column-1, len(text))
+	for idx > 0 && (isAlphaNumeric(text[idx-1])) {
+		idx--
+	}
+	for idx > 0 && (text[idx-1] == ' ' || text[idx-1] == '\t') {
+		idx--
+	}
+	return idx > 0 && text[idx-1] == '.'
+}
+
+func nativeSymbol(name string, value any) *Symbol {
+	switch value := value.(type) {
+	case nativeFunction:
+		args := "any number of arguments"
+		if value.arity() != variadicArity {
+			args = fmt.Sprintf("%d argument", value.arity())
+			if value.arity() != 1 {
+				args += "s"
+			}
+		}
+		return &Symbol{Name: name, Kind: SymbolFunction, Detail: fmt.Sprintf("native fun %s, takes %s", name, args)}
+	default:
+		return &Symbol{Name: name, Kind: SymbolModule, Detail: fmt.Sprintf("native module %s", name)}
+	}
+}
+
+func tokenLocation(t token) Location {
+	return Location{Line: t.line, Column: t.startColumn(), EndColumn: t.column}
+}
+
+/*
+records declarations and references while the resolver runs, the scopes are begun
+and ended with the resolver's so a name is looked up the same way it's resolved.
+All the methods do nothing on a nil table, which is the case when running a program.
+*/
+type symbolTable struct {
+	analysis   *Analysis
+	scopes     []map[string]*declaration
+	scopeEnds  []token
+	containers []*Symbol // functions and classes being resolved, innermost last
+	lastSymbol *Symbol
+	unresolved []int // indexes of the references not found in a local scope
+}
+
+func (t *symbolTable) beginScope() {
+	if t == nil {
+		return
+	}
+	t.scopes = append(t.scopes, make(map[string]*declaration))
+	t.scopeEnds = append(t.scopeEnds, token{})
+}
+
+func (t *symbolTable) endScope() {
+	if t == nil || len(t.scopes) == 0 {
+		return
+	}
+	t.scopes = t.scopes[:len(t.scopes)-1]
+	t.scopeEnds = t.scopeEnds[:len(t.scopeEnds)-1]
+}
+
+// where the innermost scope ends, names declared in it are visible till there
+func (t *symbolTable) scopeEndsAt(end token) {
+	if t == nil || len(t.scopeEnds) == 0 {
+		return
+	}
+	t.scopeEnds[len(t.scopeEnds)-1] = end
+}
+
+/*
+methods aren't added to the scope, as they're only reached through an object. Functions
+and classes are added to the outline of the program under the one they're declared in.
+*/
+func (t *symbolTable) declare(name token, span Span, kind SymbolKind, detail string) {
+	if t == nil {
+		return
+	}
+	symbol := &Symbol{Name: name.lexeme, Kind: kind, Detail: detail, Location: tokenLocation(name), Span: span}
+	decl := &declaration{symbol: symbol}
+	t.analysis.declarations = append(t.analysis.declarations, decl)
+	t.lastSymbol = symbol
+
+	switch {
+	case kind == SymbolMethod:
+	case len(t.scopes) == 0:
+		if _, exists := t.analysis.globals[name.lexeme]; !exists {
+			t.analysis.globals[name.lexeme] = decl
+		}
+	default:
+		t.scopes[len(t.scopes)-1][name.lexeme] = decl
+		decl.scopeEnd = t.scopeEnds[len(t.scopeEnds)-1]
+	}
+
+	if kind == SymbolFunction || kind == SymbolClass || kind == SymbolMethod {
+		if len(t.containers) == 0 {
+			t.analysis.Symbols = append(t.analysis.Symbols, symbol)
+		} else {
+			container := t.containers[len(t.containers)-1]
+			container.Children = append(container.Children, symbol)
+		}
+	}
+}
+
+// the last declared function or class contains what's declared till leave is called
+func (t *symbolTable) enter() {
+	if t == nil {
+		return
+	}
+	t.containers = append(t.containers, t.lastSymbol)
+}
+
+func (t *symbolTable) leave() {
+	if t == nil || len(t.containers) == 0 {
+		return
+	}
+	t.containers = t.containers[:len(t.containers)-1]
+}
+
+func (t *symbolTable) reference(name token) {
+	if t == nil {
+		return
+	}
+	ref := reference{location: tokenLocation(name), name: name.lexeme}
+	for idx := len(t.scopes) - 1; idx >= 0; idx-- {
+		if decl, exists := t.scopes[idx][name.lexeme]; exists {
+			ref.decl = decl
+			decl.references = append(decl.references, ref.location)
+			break
+		}
+	}
+	t.analysis.references = append(t.analysis.references, ref)
+	if ref.decl == nil {
+		t.unresolved = append(t.unresolved, len(t.analysis.references)-1)
+	}
+}
+
+// globals can be used before they're declared, like in functions called later
+func (t *symbolTable) resolveGlobals() {
+	for _, idx := range t.unresolved {
+		ref := &t.analysis.references[idx]
+		if decl, exists := t.analysis.globals[ref.name]; exists {
+			ref.decl = decl
+			decl.references = append(decl.references, ref.location)
+		}
+	}
+}
+
+func functionDetail(function sFunction, className string) string {
+	params := make([]string, len(function.parameters))
+	for idx, param := range function.parameters {
+		params[idx] = param.lexeme
+	}
+	name := function.name.lexeme
+	if className != "" {
+		name = className + "." + name
+	}
+	return fmt.Sprintf("fun %s(%s)", name, strings.Join(params, ", "))
+}
+
+func classDetail(class sClass) string {
+	if class.superclass == nil {
+		return "class " + class.name.lexeme
+	}
+	return fmt.Sprintf("class %s < %s", class.name.lexeme, class.superclass.name.lexeme)
+}
@@ -8,6 +8,7 @@ AST(abstract syntax tree) of our language.
 
 type expr interface {
 	accept(exprVisitor) (any, error)
+	span() Span // where it is in the source, see span.go
 }
 
 /*
@@ -47,11 +48,23 @@ type exprVisitor interface {
 	visitGetIndexExpr(eGetIndex) (any, error)
 	// arr[1] = 2
 	visitSetIndexExpr(eSetIndex) (any, error)
+	// "Hello ${name}!"
+	visitTemplateExpr(eTemplate) (any, error)
+	// 1:3 in arr[1:3], used as the key of index access/assignment
+	visitSliceExpr(eSlice) (any, error)
 }
 
+/*
+assignment nodes(eAssign, eSet, eSetIndex) also cover compound assignments. The
+operator is the assignment token itself - "=", "+=", "++" etc. For "++" and "--"
+the value is the literal 1. Postfix updates(a++) evaluate to the value before
+the update.
+*/
 type eAssign struct {
-	name  token
-	value expr
+	name     token
+	value    expr
+	operator token
+	postfix  bool
 }
 
 type eBinary struct {
@@ -62,16 +75,19 @@ type eBinary struct {
 
 type eCall struct {
 	callee    expr
-	paren     token // stored only for error reporting
+	paren     token // the closing ")"
 	arguments []expr
 }
 
 type eGrouping struct {
 	expression expr
+	open       token
+	close      token
 }
 
 type eLiteral struct {
 	value interface{}
+	token token // zero for the literals added by the parser, like the 1 of "a++"
 }
 
 type eLogical struct {
@@ -90,21 +106,34 @@ type eGet struct {
 type eGetIndex struct {
 	object  expr
 	key     expr
-	bracket token // stored only for error reporting
+	bracket token // the closing "]"
+}
+
+// start:end:step inside the brackets of arr[start:end:step], each part can be nil if omitted
+type eSlice struct {
+	start expr
+	end   expr
+	step  expr
+	colon token // stored only for error reporting
 }
 
 // object.name = value
 type eSet struct {
-	object expr
-	name   token
-	value  expr
+	object   expr
+	name     token
+	value    expr
+	operator token
+	postfix  bool
 }
 
+// object[key] = value
 type eSetIndex struct {
-	object  expr
-	key     expr
-	value   expr
-	bracket token // stored only for error reporting
+	object   expr
+	key      expr
+	value    expr
+	bracket  token // the closing "]"
+	operator token
+	postfix  bool
 }
 
 type eSuper struct {
@@ -128,6 +157,15 @@ type eVariable struct {
 
 type eList struct {
 	elements []expr
+	open     token
+	close    token
+}
+
+// string with embedded expressions, parts are string literals and the expressions in order
+type eTemplate struct {
+	parts []expr
+	start token // the string till the first "${"
+	end   token // the string after the last "}"
 }
 
 // define accept methods for each type of expression
@@ -191,3 +229,11 @@ func (e eGetIndex) accept(v exprVisitor) (any, error) {
 func (e eSetIndex) accept(v exprVisitor) (any, error) {
 	return v.visitSetIndexExpr(e)
 }
+
+func (e eTemplate) accept(v exprVisitor) (any, error) {
+	return v.visitTemplateExpr(e)
+}
+
+func (e eSlice) accept(v exprVisitor) (any, error) {
+	return v.visitSliceExpr(e)
+}
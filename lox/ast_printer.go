@@ -6,19 +6,138 @@ import (
 )
 
 /*
-AST Printer implements the exprVisitor, returning a string representation from the visit methods
+AST Printer implements the exprVisitor, returning a string representation from the visit methods.
+It's also the stmtVisitor, which writes statements to out as the visit methods return only errors.
 */
 
 type astPrinter struct {
+	out     *strings.Builder
+	keepFor bool // print for loops as they're written, rather than the while loops they run as
 }
 
-func (p astPrinter) print(e expr) {
+func newAstPrinter(keepFor bool) astPrinter {
+	return astPrinter{out: &strings.Builder{}, keepFor: keepFor}
+}
+
+func (p astPrinter) exprString(e expr) string {
 	val, _ := e.accept(p)
-	fmt.Println(val)
+	return val.(string)
+}
+
+/*
+for e.g. "var a = 1; print a;" => "(var a = 1.0)\n(print a)\n". Nested statements
+are on the same line as their parent.
+*/
+func (p astPrinter) printProgram(statements []stmt) string {
+	for _, st := range statements {
+		st.accept(p)
+		p.out.WriteString("\n")
+	}
+	return p.out.String()
+}
+
+func (p astPrinter) visitExprStmt(s sExpr) error {
+	return p.writeStmt(";", s.expression)
+}
+
+func (p astPrinter) visitPrintStmt(s sPrint) error {
+	return p.writeStmt("print", s.expression)
+}
+
+// "(var a)" or "(var a = 1.0)"
+func (p astPrinter) visitVarStmt(s sVar) error {
+	if s.initializer == nil {
+		return p.writeStmt("var " + s.name.lexeme)
+	}
+	return p.writeStmt("var "+s.name.lexeme+" =", s.initializer)
+}
+
+func (p astPrinter) visitBlockStmt(s sBlock) error {
+	return p.writeStmt("block", stmtParts(s.statements)...)
+}
+
+// "(if cond then)" or "(if-else cond then else)"
+func (p astPrinter) visitIfStmt(s sIf) error {
+	if s.elseBranch == nil {
+		return p.writeStmt("if", s.condition, s.thenBranch)
+	}
+	return p.writeStmt("if-else", s.condition, s.thenBranch, s.elseBranch)
+}
+
+func (p astPrinter) visitWhileStmt(s sWhile) error {
+	return p.writeStmt("while", s.condition, s.body)
+}
+
+// "(for (var i = 0.0) (< i 3.0) (post++ i) body)" where omitted parts are nil
+func (p astPrinter) visitForStmt(s sFor) error {
+	if !p.keepFor {
+		return s.desugared.accept(p)
+	}
+	parts := []any{s.initializer, s.condition, s.updater, s.body}
+	for idx, part := range parts {
+		if part == nil {
+			parts[idx] = eLiteral{value: nil}
+		}
+	}
+	return p.writeStmt("for", parts...)
+}
+
+// "(fun add(a b) (return (+ a b)))", methods are printed the same way
+func (p astPrinter) visitFunctionStmt(s sFunction) error {
+	params := make([]string, len(s.parameters))
+	for idx, param := range s.parameters {
+		params[idx] = param.lexeme
+	}
+	return p.writeStmt(fmt.Sprintf("fun %s(%s)", s.name.lexeme, strings.Join(params, " ")), stmtParts(s.body)...)
+}
+
+// "(return)" or "(return value)"
+func (p astPrinter) visitReturnStmt(s sReturn) error {
+	if s.value == nil {
+		return p.writeStmt("return")
+	}
+	return p.writeStmt("return", s.value)
+}
+
+// "(class B < A (fun method() ...))"
+func (p astPrinter) visitClassStmt(s sClass) error {
+	name := "class " + s.name.lexeme
+	if s.superclass != nil {
+		name += " < " + s.superclass.name.lexeme
+	}
+	methods := make([]any, len(s.methods))
+	for idx, method := range s.methods {
+		methods[idx] = method
+	}
+	return p.writeStmt(name, methods...)
+}
+
+func stmtParts(statements []stmt) []any {
+	parts := make([]any, len(statements))
+	for idx, st := range statements {
+		parts[idx] = st
+	}
+	return parts
+}
+
+// writes "(name part part ...)" where the parts are expressions or statements
+func (p astPrinter) writeStmt(name string, parts ...any) error {
+	p.out.WriteString("(" + name)
+	for _, part := range parts {
+		p.out.WriteString(" ")
+		switch part := part.(type) {
+		case expr:
+			p.out.WriteString(p.exprString(part))
+		case stmt:
+			part.accept(p)
+		}
+	}
+	p.out.WriteString(")")
+	return nil
 }
 
 func (p astPrinter) visitAssignExpr(e eAssign) (any, error) {
-	return p.parenthesize("= "+e.name.lexeme, e.value)
+	return p.parenthesize(assignOperatorStr(e.operator, e.postfix)+" "+e.name.lexeme, e.value)
 }
 
 /*
@@ -49,7 +168,7 @@ func (p astPrinter) visitLogicalExpr(e eLogical) (any, error) {
 }
 
 func (p astPrinter) visitSetExpr(e eSet) (any, error) {
-	return p.parenthesize("=", e.object, eLiteral{value: e.name.lexeme}, e.value)
+	return p.parenthesize(assignOperatorStr(e.operator, e.postfix), e.object, eLiteral{value: e.name.lexeme}, e.value)
 }
 
 func (p astPrinter) visitSuperExpr(e eSuper) (any, error) {
@@ -85,7 +204,33 @@ func (p astPrinter) visitGetIndexExpr(e eGetIndex) (any, error) {
 }
 
 func (p astPrinter) visitSetIndexExpr(e eSetIndex) (any, error) {
-	return p.parenthesize("setIndex", e.object, e.key, e.value)
+	name := "setIndex"
+	if e.operator.tokenType != tEqual {
+		name += " " + assignOperatorStr(e.operator, e.postfix)
+	}
+	return p.parenthesize(name, e.object, e.key, e.value)
+}
+
+func (p astPrinter) visitSliceExpr(e eSlice) (any, error) {
+	parts := []expr{e.start, e.end, e.step}
+	for idx, part := range parts {
+		if part == nil {
+			parts[idx] = eLiteral{value: nil}
+		}
+	}
+	return p.parenthesize("slice", parts...)
+}
+
+func (p astPrinter) visitTemplateExpr(e eTemplate) (any, error) {
+	return p.parenthesize("template", e.parts...)
+}
+
+// "=", "+=" etc. as is, postfix updates are marked like "post++"
+func assignOperatorStr(operator token, postfix bool) string {
+	if postfix {
+		return "post" + operator.lexeme
+	}
+	return operator.lexeme
 }
 
 func (p astPrinter) parenthesize(name string, exprs ...expr) (any, error) {
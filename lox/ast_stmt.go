@@ -6,6 +6,7 @@ Defines the statement nodes of the AST. A program is a list of statements.
 
 type stmt interface {
 	accept(stmtVisitor) error
+	span() Span // where it is in the source, see span.go
 }
 
 type stmtVisitor interface {
@@ -21,6 +22,8 @@ type stmtVisitor interface {
 	visitIfStmt(sIf) error
 	// while (a == 3) { print "hello"; a = a + 1; }
 	visitWhileStmt(sWhile) error
+	// for (var i = 0; i < 3; i++) print i;
+	visitForStmt(sFor) error
 	// fun foo() { print "hello"; }
 	visitFunctionStmt(sFunction) error
 	// return 7;
@@ -31,47 +34,76 @@ type stmtVisitor interface {
 
 type sExpr struct {
 	expression expr
+	start      token // first token of the expression
+	semicolon  token // zero for the updater of the desugared for loops
 }
 
 type sPrint struct {
+	keyword    token
 	expression expr
+	semicolon  token
 }
 
 type sVar struct {
+	keyword     token
 	name        token
 	initializer expr
+	semicolon   token
 }
 
 type sBlock struct {
 	statements []stmt
+	start      token // "{", or the "for" of the desugared for loops
+	end        token // "}", or the last token of the desugared for loops
 }
 
 type sIf struct {
+	keyword    token
 	condition  expr
 	thenBranch stmt
+	elseToken  token // zero without an else branch
 	elseBranch stmt
 }
 
 type sWhile struct {
+	keyword   token // "while", or "for" for the desugared for loops
 	condition expr
 	body      stmt
 }
 
+/*
+a for loop as it's written, kept for tools like the formatter. It runs as the
+while loop it's desugared to, in a block with the initializer.
+*/
+type sFor struct {
+	keyword     token
+	initializer stmt // sVar or sExpr, nil if omitted
+	condition   expr // nil if omitted
+	updater     expr // nil if omitted
+	body        stmt
+	desugared   stmt
+}
+
 type sClass struct {
+	keyword    token
 	name       token
 	superclass *eVariable
 	methods    []sFunction
+	end        token // "}" closing the body
 }
 
 type sFunction struct {
+	keyword    token // zero for methods, which start at their name
 	name       token
 	parameters []token
 	body       []stmt
+	end        token // "}" closing the body
 }
 
 type sReturn struct {
-	keyword token
-	value   expr
+	keyword   token
+	value     expr
+	semicolon token
 }
 
 func (e sExpr) accept(v stmtVisitor) error {
@@ -98,6 +130,10 @@ func (e sWhile) accept(v stmtVisitor) error {
 	return v.visitWhileStmt(e)
 }
 
+func (e sFor) accept(v stmtVisitor) error {
+	return v.visitForStmt(e)
+}
+
 func (e sFunction) accept(v stmtVisitor) error {
 	return v.visitFunctionStmt(e)
 }
@@ -110,7 +110,7 @@ func openImage(imagePath string) error {
 
 func (v *visualiseTreeVisitor) visitAssignExpr(e eAssign) (any, error) {
 	nodeID := v.getNextNodeID()
-	v.addNode(nodeID, "Assign", fmt.Sprintf("Assign\n%s", e.name.lexeme))
+	v.addNode(nodeID, "Assign", fmt.Sprintf("Assign %s\n%s", assignOperatorStr(e.operator, e.postfix), e.name.lexeme))
 
 	valueID := getVal(e.value.accept(v)).(string)
 	v.addEdge(nodeID, valueID)
@@ -187,7 +187,7 @@ func (v *visualiseTreeVisitor) visitLogicalExpr(e eLogical) (any, error) {
 
 func (v *visualiseTreeVisitor) visitSetExpr(e eSet) (any, error) {
 	nodeID := v.getNextNodeID()
-	v.addNode(nodeID, "Set", fmt.Sprintf("Set\n%s", e.name.lexeme))
+	v.addNode(nodeID, "Set", fmt.Sprintf("Set %s\n%s", assignOperatorStr(e.operator, e.postfix), e.name.lexeme))
 
 	objectID := getVal(e.object.accept(v)).(string)
 	valueID := getVal(e.value.accept(v)).(string)
@@ -226,6 +226,33 @@ func (v *visualiseTreeVisitor) visitVariableExpr(e eVariable) (any, error) {
 	return nodeID, nil
 }
 
+func (v *visualiseTreeVisitor) visitTemplateExpr(e eTemplate) (any, error) {
+	nodeID := v.getNextNodeID()
+	v.addNode(nodeID, "Template", "Template")
+
+	for _, part := range e.parts {
+		partID := getVal(part.accept(v)).(string)
+		v.addEdge(nodeID, partID)
+	}
+
+	return nodeID, nil
+}
+
+func (v *visualiseTreeVisitor) visitSliceExpr(e eSlice) (any, error) {
+	nodeID := v.getNextNodeID()
+	v.addNode(nodeID, "Slice", "Slice")
+
+	for _, part := range []expr{e.start, e.end, e.step} {
+		if part == nil {
+			part = eLiteral{value: nil}
+		}
+		partID := getVal(part.accept(v)).(string)
+		v.addEdge(nodeID, partID)
+	}
+
+	return nodeID, nil
+}
+
 func (v *visualiseTreeVisitor) visitListExpr(e eList) (any, error) {
 	panic("not implemented")
 }
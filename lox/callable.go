@@ -3,21 +3,23 @@ package lox
 import (
 	"errors"
 	"fmt"
-	"math"
-	"math/rand/v2"
+	"io"
 	"strconv"
 	"time"
 )
 
 type callable interface {
-	arity() int // number of arguments needed
+	arity() int // number of arguments needed, variadicArity if it can be any number
 	call(interpreter interpreter, arguments []any) (any, error)
 	String() string
 }
 
+const variadicArity = -1
+
 type nativeFunction struct {
 	arityCnt int
 	fn       func(interpreter, []any) (any, error)
+	name     string // of methods like map, for stack traces
 }
 
 type loxFunction struct {
@@ -34,6 +36,91 @@ func (r returnAsError) Error() string {
 	return fmt.Sprintf("return statement with value %v", r.value)
 }
 
+/*
+exit(code) in lox panics with this like a runtime error does, so it unwinds through
+any depth of calls. It's recovered in Run which returns the code as the exit code.
+*/
+type exitRequest struct {
+	code int
+}
+
+/*
+returned by native functions when they're called wrongly, for e.g. with an argument
+of wrong type. It's reported as a runtime error at the call site.
+*/
+type nativeError struct {
+	msg string
+}
+
+func (e nativeError) Error() string {
+	return e.msg
+}
+
+func nativeErrorf(format string, a ...any) nativeError {
+	return nativeError{msg: fmt.Sprintf(format, a...)}
+}
+
+// for natives with optional arguments, which are declared with variadicArity
+func argCountBetween(fnName string, args []any, minCnt, maxCnt int) error {
+	if len(args) < minCnt || len(args) > maxCnt {
+		return nativeErrorf("%s() expects %d to %d arguments but got %d.", fnName, minCnt, maxCnt, len(args))
+	}
+	return nil
+}
+
+// helpers to validate and convert the arguments of native functions, position is 0 based
+
+func stringArg(fnName string, args []any, position int) (string, error) {
+	str, ok := args[position].(string)
+	if !ok {
+		return "", nativeErrorf("%s() expects argument %d to be a string.", fnName, position+1)
+	}
+	return str, nil
+}
+
+func numberArg(fnName string, args []any, position int) (float64, error) {
+	num, ok := args[position].(float64)
+	if !ok {
+		return 0, nativeErrorf("%s() expects argument %d to be a number.", fnName, position+1)
+	}
+	return num, nil
+}
+
+func intArg(fnName string, args []any, position int) (int, error) {
+	if !isInteger(args[position]) {
+		return 0, nativeErrorf("%s() expects argument %d to be an integer.", fnName, position+1)
+	}
+	return int(args[position].(float64)), nil
+}
+
+func callableArg(fnName string, args []any, position int) (callable, error) {
+	fn, ok := args[position].(callable)
+	if !ok {
+		return nil, nativeErrorf("%s() expects argument %d to be a function.", fnName, position+1)
+	}
+	return fn, nil
+}
+
+func listArg(fnName string, args []any, position int) (*loxList, error) {
+	list, ok := args[position].(*loxList)
+	if !ok {
+		return nil, nativeErrorf("%s() expects argument %d to be a list.", fnName, position+1)
+	}
+	return list, nil
+}
+
+// reading input from the host, the end of input is nil in lox
+func inputResult(fnName string, read func() (string, error)) (any, error) {
+	str, err := read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, nativeErrorf("%s() failed, %s.", fnName, err)
+	}
+	return str, nil
+}
+
 var _ callable = nativeFunction{} // assert interface adherence
 var _ callable = loxFunction{}    // assert interface adherence
 
@@ -60,6 +147,10 @@ func (f loxFunction) bind(instance loxClassInstance) loxFunction {
 }
 
 func (f loxFunction) call(i interpreter, arguments []any) (any, error) {
+	if i.debugger != nil {
+		i.debugger.enterFunction(f.declaration.name.lexeme)
+		defer i.debugger.exitFunction()
+	}
 	env := newChildEnvironment(f.closure)
 	for i, param := range f.declaration.parameters {
 		env.define(param.lexeme, arguments[i])
@@ -91,16 +182,21 @@ func (f loxFunction) String() string {
 Theses are built-in functions that will be available natively in lox.
 */
 func defineNativeFunctions(globals *environment) {
-	globals.define("clock", nativeFunction{
+	defineMathFunctions(globals)
+	defineFileFunctions(globals)
+	defineJSONFunctions(globals)
+	defineProcessFunctions(globals)
+	defineTimeFunctions(globals)
+	globals.define("clock", nativeFunction{ // unix time in milliseconds
 		fn: func(i interpreter, a []any) (any, error) {
-			timeInt := time.Now().UnixMilli()
+			timeInt := clock.Now().UnixMilli()
 			return float64(timeInt), nil
 		},
 	})
-	globals.define("sleep", nativeFunction{ // sleep in seconds
+	globals.define("sleep", nativeFunction{ // sleep in milliseconds
 		arityCnt: 1,
 		fn: func(i interpreter, a []any) (any, error) {
-			time.Sleep(time.Duration(a[0].(float64)) * time.Millisecond)
+			clock.Sleep(time.Duration(a[0].(float64)) * time.Millisecond)
 			return nil, nil
 		},
 	})
@@ -111,16 +207,42 @@ func defineNativeFunctions(globals *environment) {
 			return nil, nil
 		},
 	})
-	globals.define("input", nativeFunction{
+	globals.define("input", nativeFunction{ // shows the prompt and reads a line, the end of input is an error
 		arityCnt: 1,
 		fn: func(i interpreter, a []any) (any, error) {
-			return logger.Input(a[0].(string))
+			prompt, err := stringArg("input", a, 0)
+			if err != nil {
+				return nil, err
+			}
+			line, err := logger.Input(prompt)
+			if err != nil {
+				return nil, nativeErrorf("input() failed, %s.", err)
+			}
+			return line, nil
+		},
+	})
+	globals.define("readLine", nativeFunction{ // nil at the end of input
+		fn: func(i interpreter, a []any) (any, error) {
+			return inputResult("readLine", logger.ReadLine)
+		},
+	})
+	globals.define("readAll", nativeFunction{ // for piping whole files to the program
+		fn: func(i interpreter, a []any) (any, error) {
+			return inputResult("readAll", logger.ReadAll)
 		},
 	})
 	globals.define("parseNumber", nativeFunction{
 		arityCnt: 1,
 		fn: func(i interpreter, a []any) (any, error) {
-			return strconv.ParseFloat(a[0].(string), 64)
+			str, err := stringArg("parseNumber", a, 0)
+			if err != nil {
+				return nil, err
+			}
+			num, err := strconv.ParseFloat(str, 64)
+			if err != nil {
+				return nil, nativeErrorf("parseNumber() can't parse '%s'.", str)
+			}
+			return num, nil
 		},
 	})
 	globals.define("string", nativeFunction{
@@ -132,8 +254,17 @@ func defineNativeFunctions(globals *environment) {
 	globals.define("randInt", nativeFunction{
 		arityCnt: 1,
 		fn: func(i interpreter, a []any) (any, error) {
-			numInt := rand.IntN(int(a[0].(float64)))
-			return float64(numInt), nil
+			n, err := numberArg("randInt", a, 0)
+			if err != nil {
+				return nil, err
+			}
+			if n < 1 {
+				return nil, nativeErrorf("randInt() expects a number of at least 1.")
+			}
+			if !fitsInt64(n) {
+				return nil, nativeErrorf("randInt() expects a number which fits in a 64 bit integer.")
+			}
+			return float64(rng.IntN(int(n))), nil
 		},
 	})
 	globals.define("clear", nativeFunction{
@@ -143,16 +274,25 @@ func defineNativeFunctions(globals *environment) {
 			return nil, nil
 		},
 	})
-	globals.define("floor", nativeFunction{
+	globals.define("ord", nativeFunction{ // gives the ascii value of a character
 		arityCnt: 1,
 		fn: func(i interpreter, a []any) (any, error) {
-			return math.Floor(a[0].(float64)), nil
+			return float64(a[0].(string)[0]), nil
 		},
 	})
-	globals.define("ord", nativeFunction{ // gives the ascii value of a character
+	globals.define("map", nativeFunction{ // creates an empty map
+		fn: func(i interpreter, a []any) (any, error) {
+			return getLoxMap(), nil
+		},
+	})
+	globals.define("regex", nativeFunction{ // compiles the pattern, regex("[0-9]+").findAll(str)
 		arityCnt: 1,
 		fn: func(i interpreter, a []any) (any, error) {
-			return float64(a[0].(string)[0]), nil
+			pattern, err := stringArg("regex", a, 0)
+			if err != nil {
+				return nil, err
+			}
+			return compileRegex(pattern)
 		},
 	})
 	globals.define("len", nativeFunction{
@@ -163,6 +303,8 @@ func defineNativeFunctions(globals *environment) {
 				return float64(len(a[0].(*loxList).elements)), nil
 			case string:
 				return float64(len(a[0].(string))), nil
+			case *loxMap:
+				return float64(len(a[0].(*loxMap).keys)), nil
 			default:
 				// fmt.Printf("type of %v is %T\n", a[0], a[0])
 				logRuntimeError(token{}, "len() can only be called on iterables.")
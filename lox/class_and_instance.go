@@ -51,6 +51,17 @@ func (c loxClass) findMethod(name string) (loxFunction, bool) {
 	return method, ok
 }
 
+// of the class and its superclasses
+func (c loxClass) methodNames() []string {
+	var names []string
+	for class := &c; class != nil; class = class.superclass {
+		for name := range class.methods {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 func (i loxClassInstance) String() string {
 	return i.klass.name + " instance"
 }
@@ -65,7 +76,11 @@ func (i loxClassInstance) get(name token) any {
 		return method.bind(i)
 	}
 
-	logRuntimeError(name, "Undefined property '"+name.lexeme+"'.")
+	names := i.klass.methodNames()
+	for field := range i.fields {
+		names = append(names, field)
+	}
+	logUndefinedError(name, "Undefined property '"+name.lexeme+"'.", names)
 	return nil
 }
 
@@ -0,0 +1,54 @@
+package lox
+
+import "time"
+
+/*
+Clock used by clock(), sleep() and the time module. Hosts can set a fake clock
+so that programs working with time give the same output on every run.
+*/
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+var clock Clock = systemClock{}
+
+func SetClock(clock2 Clock) {
+	clock = clock2
+}
+
+type systemClock struct{}
+
+func NewSystemClock() Clock {
+	return systemClock{}
+}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+/*
+starts at the given time and only moves forward when something sleeps, which
+returns immediately.
+*/
+type fakeClock struct {
+	now time.Time
+}
+
+func NewFakeClock(start time.Time) Clock {
+	return &fakeClock{now: start}
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+func (f *fakeClock) Sleep(d time.Duration) {
+	if d > 0 {
+		f.now = f.now.Add(d)
+	}
+}
@@ -1,7 +1,9 @@
 package lox
 
 import (
+	"cmp"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -27,13 +29,12 @@ func (l *loxList) getMethod(name token) callable {
 	}
 	return nativeFunction{
 		arityCnt: arityCnt,
-		fn: func(i interpreter, a []any) (any, error) {
-			return method(a), nil
-		},
+		fn:       method,
+		name:     name.lexeme,
 	}
 }
 
-func (l *loxList) getMethodAndArity(name token) (int, func(args []any) any) {
+func (l *loxList) getMethodAndArity(name token) (int, func(i interpreter, args []any) (any, error)) {
 	switch name.lexeme {
 	case "append":
 		return 1, l.append
@@ -47,6 +48,30 @@ func (l *loxList) getMethodAndArity(name token) (int, func(args []any) any) {
 		return 2, l.insert
 	case "concat":
 		return 1, l.concat
+	case "map":
+		return 1, l.mapList
+	case "filter":
+		return 1, l.filter
+	case "reduce":
+		return variadicArity, l.reduce
+	case "forEach":
+		return 1, l.forEach
+	case "find":
+		return 1, l.find
+	case "any":
+		return 1, l.any
+	case "all":
+		return 1, l.all
+	case "indexOf":
+		return 1, l.indexOf
+	case "contains":
+		return 1, l.contains
+	case "reverse":
+		return 0, l.reverse
+	case "slice":
+		return variadicArity, l.slice
+	case "sort":
+		return variadicArity, l.sort
 	default:
 		return 0, nil
 	}
@@ -56,7 +81,7 @@ func (l *loxList) getAtIndex(index int) any {
 	if index < 0 {
 		index = len(l.elements) + index
 	}
-	if index >= len(l.elements) {
+	if index < 0 || index >= len(l.elements) {
 		logRuntimeError(token{}, "Index out of bounds")
 		return nil
 	}
@@ -67,7 +92,7 @@ func (l *loxList) setAtIndex(index int, value any) any {
 	if index < 0 {
 		index = len(l.elements) + index
 	}
-	if index >= len(l.elements) {
+	if index < 0 || index >= len(l.elements) {
 		logRuntimeError(token{}, "Index out of bounds")
 		return nil
 	}
@@ -75,47 +100,366 @@ func (l *loxList) setAtIndex(index int, value any) any {
 	return value
 }
 
-func (l *loxList) append(args []any) any {
+// arr[start:end:step], gives a new list
+func (l *loxList) getSlice(r sliceRange) *loxList {
+	newList := getLoxList([]any{})
+	for _, index := range r.indices(len(l.elements)) {
+		newList.elements = append(newList.elements, l.elements[index])
+	}
+	return newList
+}
+
+/*
+arr[start:end] = values, the selected part is replaced with the values which can
+be of a different length. For slices with a step, the length must match as each
+selected position is replaced in place.
+*/
+func (l *loxList) setSlice(r sliceRange, values []any) error {
+	if r.step == nil || *r.step == 1 {
+		start, end := r.bounds(len(l.elements))
+		end = max(start, end)
+		newElements := make([]any, 0, len(l.elements)-(end-start)+len(values))
+		newElements = append(newElements, l.elements[:start]...)
+		newElements = append(newElements, values...)
+		newElements = append(newElements, l.elements[end:]...)
+		l.elements = newElements
+		return nil
+	}
+
+	indices := r.indices(len(l.elements))
+	if len(indices) != len(values) {
+		return fmt.Errorf("Can't assign %d elements to a slice of size %d.", len(values), len(indices))
+	}
+	for idx, index := range indices {
+		l.elements[index] = values[idx]
+	}
+	return nil
+}
+
+func (l *loxList) append(i interpreter, args []any) (any, error) {
 	l.elements = append(l.elements, args[0])
-	return l
+	return l, nil
 }
 
-func (l *loxList) extend(args []any) any {
-	l.elements = append(l.elements, args[0].(*loxList).elements...)
-	return l
+func (l *loxList) extend(i interpreter, args []any) (any, error) {
+	other, err := listArg("extend", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	l.elements = append(l.elements, other.elements...)
+	return l, nil
 }
 
-func (l *loxList) pop(args []any) any {
+func (l *loxList) pop(i interpreter, args []any) (any, error) {
+	if len(l.elements) == 0 {
+		return nil, nativeErrorf("pop() called on an empty list.")
+	}
 	last := len(l.elements) - 1
 	val := l.elements[last]
 	l.elements = l.elements[:last]
-	return val
+	return val, nil
 }
 
-func (l *loxList) remove(args []any) any {
-	index := int(args[0].(float64))
+func (l *loxList) remove(i interpreter, args []any) (any, error) {
+	index, err := intArg("remove", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(l.elements) {
+		return nil, nativeErrorf("remove() index out of bounds.")
+	}
 	l.elements = append(l.elements[:index], l.elements[index+1:]...)
-	return l
+	return l, nil
 }
 
-func (l *loxList) insert(args []any) any {
-	index := int(args[0].(float64))
+func (l *loxList) insert(i interpreter, args []any) (any, error) {
+	index, err := intArg("insert", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index > len(l.elements) {
+		return nil, nativeErrorf("insert() index out of bounds.")
+	}
 	element := args[1]
 	l.elements = append(l.elements, nil)
 	copy(l.elements[index+1:], l.elements[index:])
 	l.elements[index] = element
-	return l
+	return l, nil
 }
 
 // creates a new list instead of modifying the existing one like extend
-func (l *loxList) concat(args []any) any {
-	other := args[0].(*loxList)
+func (l *loxList) concat(i interpreter, args []any) (any, error) {
+	other, err := listArg("concat", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return l.concatList(other), nil
+}
+
+func (l *loxList) concatList(other *loxList) *loxList {
 	newList := loxList{}
 	newList.elements = append(newList.elements, l.elements...)
 	newList.elements = append(newList.elements, other.elements...)
 	return &newList
 }
 
+/*
+calls the lox function passed to methods like map and filter for an element. The
+callback can either take just the element, or the element and its index.
+*/
+func callForElement(i interpreter, fnName string, fn callable, elem any, index int) (any, error) {
+	switch fn.arity() {
+	case 1, variadicArity:
+		return callCallback(i, fn, []any{elem})
+	case 2:
+		return callCallback(i, fn, []any{elem, float64(index)})
+	default:
+		return nil, nativeErrorf("%s() callback must take 1 or 2 arguments.", fnName)
+	}
+}
+
+// [1, 2].map(fun (x) { return x * 2; }) => [2, 4]
+func (l *loxList) mapList(i interpreter, args []any) (any, error) {
+	fn, err := callableArg("map", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	elements := make([]any, len(l.elements))
+	for idx, elem := range l.elements {
+		if elements[idx], err = callForElement(i, "map", fn, elem, idx); err != nil {
+			return nil, err
+		}
+	}
+	return getLoxList(elements), nil
+}
+
+// new list with the elements for which the callback returns a truthy value
+func (l *loxList) filter(i interpreter, args []any) (any, error) {
+	fn, err := callableArg("filter", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	elements := []any{}
+	for idx, elem := range l.elements {
+		keep, err := callForElement(i, "filter", fn, elem, idx)
+		if err != nil {
+			return nil, err
+		}
+		if isTruthy(keep) {
+			elements = append(elements, elem)
+		}
+	}
+	return getLoxList(elements), nil
+}
+
+/*
+list.reduce(fn, initial), fn is called with the accumulated value and the element.
+Without the initial value, the first element is used as the initial value.
+*/
+func (l *loxList) reduce(i interpreter, args []any) (any, error) {
+	if err := argCountBetween("reduce", args, 1, 2); err != nil {
+		return nil, err
+	}
+	fn, err := callableArg("reduce", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if fn.arity() != 2 && fn.arity() != variadicArity {
+		return nil, nativeErrorf("reduce() callback must take 2 arguments.")
+	}
+
+	elements := l.elements
+	var acc any
+	if len(args) == 2 {
+		acc = args[1]
+	} else if len(elements) == 0 {
+		return nil, nativeErrorf("reduce() of an empty list with no initial value.")
+	} else {
+		acc = elements[0]
+		elements = elements[1:]
+	}
+	for _, elem := range elements {
+		if acc, err = callCallback(i, fn, []any{acc, elem}); err != nil {
+			return nil, err
+		}
+	}
+	return acc, nil
+}
+
+func (l *loxList) forEach(i interpreter, args []any) (any, error) {
+	fn, err := callableArg("forEach", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	for idx, elem := range l.elements {
+		if _, err := callForElement(i, "forEach", fn, elem, idx); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// first element for which the callback returns a truthy value, nil if there is none
+func (l *loxList) find(i interpreter, args []any) (any, error) {
+	idx, err := l.findIndex(i, "find", args)
+	if err != nil || idx == -1 {
+		return nil, err
+	}
+	return l.elements[idx], nil
+}
+
+func (l *loxList) any(i interpreter, args []any) (any, error) {
+	idx, err := l.findIndex(i, "any", args)
+	if err != nil {
+		return nil, err
+	}
+	return idx != -1, nil
+}
+
+func (l *loxList) all(i interpreter, args []any) (any, error) {
+	fn, err := callableArg("all", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	for idx, elem := range l.elements {
+		ok, err := callForElement(i, "all", fn, elem, idx)
+		if err != nil {
+			return nil, err
+		}
+		if !isTruthy(ok) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// index of the first element for which the callback passed as args[0] is truthy, -1 if none
+func (l *loxList) findIndex(i interpreter, fnName string, args []any) (int, error) {
+	fn, err := callableArg(fnName, args, 0)
+	if err != nil {
+		return -1, err
+	}
+	for idx, elem := range l.elements {
+		ok, err := callForElement(i, fnName, fn, elem, idx)
+		if err != nil {
+			return -1, err
+		}
+		if isTruthy(ok) {
+			return idx, nil
+		}
+	}
+	return -1, nil
+}
+
+// index of the first element equal to the value, -1 if it's not present
+func (l *loxList) indexOf(i interpreter, args []any) (any, error) {
+	for idx, elem := range l.elements {
+		if checkEqua(elem, args[0]) {
+			return float64(idx), nil
+		}
+	}
+	return -1.0, nil
+}
+
+func (l *loxList) contains(i interpreter, args []any) (any, error) {
+	idx, _ := l.indexOf(i, args)
+	return idx != -1.0, nil
+}
+
+// reverses the list in place
+func (l *loxList) reverse(i interpreter, args []any) (any, error) {
+	slices.Reverse(l.elements)
+	return l, nil
+}
+
+// list.slice(start, end), same as list[start:end], end is optional
+func (l *loxList) slice(i interpreter, args []any) (any, error) {
+	if err := argCountBetween("slice", args, 1, 2); err != nil {
+		return nil, err
+	}
+	var r sliceRange
+	for position := range args {
+		if args[position] == nil {
+			continue
+		}
+		index, err := intArg("slice", args, position)
+		if err != nil {
+			return nil, err
+		}
+		if position == 0 {
+			r.start = &index
+		} else {
+			r.end = &index
+		}
+	}
+	return l.getSlice(r), nil
+}
+
+/*
+stable sort in place. Without a comparator, numbers and strings are sorted in
+ascending order. The comparator gets two elements and returns a negative number
+if the first one should come first, positive if second one, and 0 if they're equal.
+*/
+func (l *loxList) sort(i interpreter, args []any) (any, error) {
+	if err := argCountBetween("sort", args, 0, 1); err != nil {
+		return nil, err
+	}
+	var comparator callable
+	if len(args) == 1 {
+		fn, err := callableArg("sort", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		if fn.arity() != 2 && fn.arity() != variadicArity {
+			return nil, nativeErrorf("sort() comparator must take 2 arguments.")
+		}
+		comparator = fn
+	}
+
+	// the first error stops the comparisons, the order of the list doesn't matter after that
+	var sortErr error
+	slices.SortStableFunc(l.elements, func(a, b any) int {
+		if sortErr != nil {
+			return 0
+		}
+		if comparator == nil {
+			result, err := compareValues(a, b)
+			sortErr = err
+			return result
+		}
+		result, err := callCallback(i, comparator, []any{a, b})
+		if err != nil {
+			sortErr = err
+			return 0
+		}
+		num, ok := result.(float64)
+		if !ok {
+			sortErr = nativeErrorf("sort() comparator must return a number.")
+			return 0
+		}
+		return cmp.Compare(num, 0)
+	})
+	if sortErr != nil {
+		return nil, sortErr
+	}
+	return l, nil
+}
+
+// default ordering used by sort, only numbers with numbers and strings with strings
+func compareValues(a, b any) (int, error) {
+	switch a := a.(type) {
+	case float64:
+		if b, ok := b.(float64); ok {
+			return cmp.Compare(a, b), nil
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return cmp.Compare(a, b), nil
+		}
+	}
+	return 0, nativeErrorf("sort() can only compare two numbers or two strings, pass a comparator for other values.")
+}
+
 func (l *loxList) String() string {
 	var sb strings.Builder
 	sb.WriteString("[")
@@ -141,7 +485,71 @@ func arrayElementToStr(literal interface{}) string {
 		return strconv.FormatFloat(literal, 'f', -1, 64)
 	case *loxList:
 		return literal.String()
+	case *loxMap:
+		return literal.String()
 	default:
 		return fmt.Sprintf("\"%s\"", getLiteralStr(literal))
 	}
 }
+
+/*
+evaluated form of start:end:step in arr[start:end:step], nil for the parts which
+were omitted. step is never 0.
+*/
+type sliceRange struct {
+	start *int
+	end   *int
+	step  *int
+}
+
+/*
+works like python, negative values count from the end, and out of range values
+are clamped to the sequence instead of being an error.
+*/
+func (r sliceRange) bounds(length int) (int, int) {
+	step := 1
+	if r.step != nil {
+		step = *r.step
+	}
+	// for negative step, -1 means before the first element
+	lower, upper := 0, length
+	if step < 0 {
+		lower, upper = -1, length-1
+	}
+	clamp := func(index *int, defaultVal int) int {
+		if index == nil {
+			return defaultVal
+		}
+		val := *index
+		if val < 0 {
+			val += length
+		}
+		return min(max(val, lower), upper)
+	}
+	if step > 0 {
+		return clamp(r.start, lower), clamp(r.end, upper)
+	}
+	return clamp(r.start, upper), clamp(r.end, lower)
+}
+
+// indices of a sequence of given length selected by the slice, in order
+func (r sliceRange) indices(length int) []int {
+	step := 1
+	if r.step != nil {
+		step = *r.step
+	}
+	start, end := r.bounds(length)
+	var indices []int
+	for index := start; (step > 0 && index < end) || (step < 0 && index > end); index += step {
+		indices = append(indices, index)
+	}
+	return indices
+}
+
+func sliceString(str string, r sliceRange) string {
+	var sb strings.Builder
+	for _, index := range r.indices(len(str)) {
+		sb.WriteByte(str[index])
+	}
+	return sb.String()
+}
@@ -0,0 +1,144 @@
+package lox
+
+import (
+	"slices"
+	"strings"
+)
+
+/*
+map with string keys, created with map() or by jsonParse. Accessed by key like
+m["name"] = 3. Keys are kept in insertion order so printing and iterating over
+keys() is deterministic.
+*/
+type loxMap struct {
+	keys   []string
+	values map[string]any
+}
+
+var _ dataType = &loxMap{}
+
+func getLoxMap() *loxMap {
+	return &loxMap{values: make(map[string]any)}
+}
+
+func (m *loxMap) getMethod(name token) callable {
+	arityCnt, method := m.getMethodAndArity(name)
+	if method == nil {
+		return nil
+	}
+	return nativeFunction{
+		arityCnt: arityCnt,
+		fn:       method,
+		name:     name.lexeme,
+	}
+}
+
+func (m *loxMap) getMethodAndArity(name token) (int, func(i interpreter, args []any) (any, error)) {
+	switch name.lexeme {
+	case "keys":
+		return 0, m.keysList
+	case "values":
+		return 0, m.valuesList
+	case "has":
+		return 1, m.has
+	case "get":
+		return variadicArity, m.getOr
+	case "remove":
+		return 1, m.remove
+	default:
+		return 0, nil
+	}
+}
+
+// key used in m[key], only strings are allowed as keys
+func mapKey(key any, bracket token) string {
+	str, ok := key.(string)
+	if !ok {
+		logRuntimeError(bracket, "Map keys must be strings.")
+	}
+	return str
+}
+
+func (m *loxMap) get(key string) (any, bool) {
+	val, ok := m.values[key]
+	return val, ok
+}
+
+func (m *loxMap) set(key string, value any) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *loxMap) keysList(i interpreter, args []any) (any, error) {
+	elements := make([]any, len(m.keys))
+	for idx, key := range m.keys {
+		elements[idx] = key
+	}
+	return getLoxList(elements), nil
+}
+
+func (m *loxMap) valuesList(i interpreter, args []any) (any, error) {
+	elements := make([]any, len(m.keys))
+	for idx, key := range m.keys {
+		elements[idx] = m.values[key]
+	}
+	return getLoxList(elements), nil
+}
+
+func (m *loxMap) has(i interpreter, args []any) (any, error) {
+	key, err := stringArg("has", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	_, ok := m.values[key]
+	return ok, nil
+}
+
+// m.get(key, default), gives the default(nil if not passed) when the key is missing
+func (m *loxMap) getOr(i interpreter, args []any) (any, error) {
+	if err := argCountBetween("get", args, 1, 2); err != nil {
+		return nil, err
+	}
+	key, err := stringArg("get", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if val, ok := m.values[key]; ok {
+		return val, nil
+	}
+	if len(args) == 2 {
+		return args[1], nil
+	}
+	return nil, nil
+}
+
+// removes the key and gives its value, nil if the key wasn't there
+func (m *loxMap) remove(i interpreter, args []any) (any, error) {
+	key, err := stringArg("remove", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	val, ok := m.values[key]
+	if ok {
+		delete(m.values, key)
+		m.keys = slices.DeleteFunc(m.keys, func(k string) bool { return k == key })
+	}
+	return val, nil
+}
+
+func (m *loxMap) String() string {
+	var sb strings.Builder
+	sb.WriteString("{")
+	for idx, key := range m.keys {
+		if idx > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(arrayElementToStr(key))
+		sb.WriteString(": ")
+		sb.WriteString(arrayElementToStr(m.values[key]))
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
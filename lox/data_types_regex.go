@@ -0,0 +1,129 @@
+package lox
+
+import (
+	"errors"
+	"regexp"
+	"regexp/syntax"
+)
+
+/*
+compiled regular expression, created with regex(pattern). Uses go's RE2 syntax,
+so matching always runs in linear time. A match is given as a list of the whole
+matched text followed by the capture groups, with nil for groups which didn't
+participate in the match.
+*/
+type loxRegex struct {
+	re *regexp.Regexp
+}
+
+var _ dataType = &loxRegex{}
+
+func compileRegex(pattern string) (*loxRegex, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		var syntaxErr *syntax.Error
+		if errors.As(err, &syntaxErr) {
+			return nil, nativeErrorf("regex() invalid pattern, %s: `%s`.", syntaxErr.Code, syntaxErr.Expr)
+		}
+		return nil, nativeErrorf("regex() invalid pattern, %s.", err)
+	}
+	return &loxRegex{re: re}, nil
+}
+
+func (r *loxRegex) getMethod(name token) callable {
+	arityCnt, method := r.getMethodAndArity(name)
+	if method == nil {
+		return nil
+	}
+	return nativeFunction{
+		arityCnt: arityCnt,
+		fn:       method,
+		name:     name.lexeme,
+	}
+}
+
+func (r *loxRegex) getMethodAndArity(name token) (int, func(i interpreter, args []any) (any, error)) {
+	switch name.lexeme {
+	case "test":
+		return 1, r.test
+	case "match":
+		return 1, r.match
+	case "findAll":
+		return 1, r.findAll
+	case "replace":
+		return 2, r.replace
+	default:
+		return 0, nil
+	}
+}
+
+// whether the pattern matches anywhere in the string
+func (r *loxRegex) test(i interpreter, args []any) (any, error) {
+	str, err := stringArg("test", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return r.re.MatchString(str), nil
+}
+
+// the first match as [whole, group1, ...], nil if there is no match
+func (r *loxRegex) match(i interpreter, args []any) (any, error) {
+	str, err := stringArg("match", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	indices := r.re.FindStringSubmatchIndex(str)
+	if indices == nil {
+		return nil, nil
+	}
+	return r.matchList(str, indices), nil
+}
+
+/*
+all non overlapping matches. Without capture groups each match is the matched
+string, otherwise it's a list like the one given by match.
+*/
+func (r *loxRegex) findAll(i interpreter, args []any) (any, error) {
+	str, err := stringArg("findAll", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	elements := []any{}
+	for _, indices := range r.re.FindAllStringSubmatchIndex(str, -1) {
+		if r.re.NumSubexp() == 0 {
+			elements = append(elements, str[indices[0]:indices[1]])
+		} else {
+			elements = append(elements, r.matchList(str, indices))
+		}
+	}
+	return getLoxList(elements), nil
+}
+
+// replaces all matches, $1 or $name in the replacement refer to the capture groups.
+// ${1} is written as "\${1}" in lox, as ${} in a string literal is interpolation.
+func (r *loxRegex) replace(i interpreter, args []any) (any, error) {
+	str, err := stringArg("replace", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	replacement, err := stringArg("replace", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return r.re.ReplaceAllString(str, replacement), nil
+}
+
+func (r *loxRegex) matchList(str string, indices []int) *loxList {
+	elements := make([]any, len(indices)/2)
+	for idx := range elements {
+		start, end := indices[2*idx], indices[2*idx+1]
+		if start >= 0 {
+			elements[idx] = str[start:end]
+		}
+	}
+	return getLoxList(elements)
+}
+
+func (r *loxRegex) String() string {
+	return "<regex " + r.re.String() + ">"
+}
@@ -0,0 +1,213 @@
+package lox
+
+import (
+	"strconv"
+	"strings"
+)
+
+/*
+strings are stored as plain go strings in the interpreter, this type wraps them
+to expose methods like "a,b".split(",") through the dataType interface.
+Like indexing, positions are byte based.
+*/
+type loxString string
+
+var _ dataType = loxString("")
+
+func (s loxString) getMethod(name token) callable {
+	arityCnt, method := s.getMethodAndArity(name)
+	if method == nil {
+		return nil
+	}
+	return nativeFunction{
+		arityCnt: arityCnt,
+		fn:       method,
+		name:     name.lexeme,
+	}
+}
+
+func (s loxString) getMethodAndArity(name token) (int, func(i interpreter, args []any) (any, error)) {
+	switch name.lexeme {
+	case "split":
+		return 1, s.split
+	case "join":
+		return 1, s.join
+	case "upper":
+		return 0, s.upper
+	case "lower":
+		return 0, s.lower
+	case "trim":
+		return 0, s.trim
+	case "startsWith":
+		return 1, s.startsWith
+	case "endsWith":
+		return 1, s.endsWith
+	case "find":
+		return 1, s.find
+	case "replace":
+		return 2, s.replace
+	case "repeat":
+		return 1, s.repeat
+	case "chars":
+		return 0, s.chars
+	case "format":
+		return variadicArity, s.format
+	default:
+		return 0, nil
+	}
+}
+
+// "a,b,c".split(",") => ["a", "b", "c"], an empty separator splits into characters
+func (s loxString) split(i interpreter, args []any) (any, error) {
+	sep, err := stringArg("split", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if sep == "" {
+		return s.chars(i, args)
+	}
+	var elements []any
+	for _, part := range strings.Split(string(s), sep) {
+		elements = append(elements, part)
+	}
+	return getLoxList(elements), nil
+}
+
+// ", ".join(["a", "b"]) => "a, b", the elements are converted to string like print does
+func (s loxString) join(i interpreter, args []any) (any, error) {
+	list, err := listArg("join", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]string, len(list.elements))
+	for idx, elem := range list.elements {
+		parts[idx] = getLiteralStr(elem)
+	}
+	return strings.Join(parts, string(s)), nil
+}
+
+func (s loxString) upper(i interpreter, args []any) (any, error) {
+	return strings.ToUpper(string(s)), nil
+}
+
+func (s loxString) lower(i interpreter, args []any) (any, error) {
+	return strings.ToLower(string(s)), nil
+}
+
+// removes whitespace from both ends
+func (s loxString) trim(i interpreter, args []any) (any, error) {
+	return strings.TrimSpace(string(s)), nil
+}
+
+func (s loxString) startsWith(i interpreter, args []any) (any, error) {
+	prefix, err := stringArg("startsWith", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return strings.HasPrefix(string(s), prefix), nil
+}
+
+func (s loxString) endsWith(i interpreter, args []any) (any, error) {
+	suffix, err := stringArg("endsWith", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return strings.HasSuffix(string(s), suffix), nil
+}
+
+// index of the first occurrence of the substring, -1 if it's not present
+func (s loxString) find(i interpreter, args []any) (any, error) {
+	sub, err := stringArg("find", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return float64(strings.Index(string(s), sub)), nil
+}
+
+// replaces all occurrences of the first argument with the second
+func (s loxString) replace(i interpreter, args []any) (any, error) {
+	old, err := stringArg("replace", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	new, err := stringArg("replace", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return strings.ReplaceAll(string(s), old, new), nil
+}
+
+// the longest string repeat makes, a huge count is more likely a bug than what's wanted
+const maxRepeatLength = 1 << 28
+
+func (s loxString) repeat(i interpreter, args []any) (any, error) {
+	if _, err := intArg("repeat", args, 0); err != nil {
+		return nil, err
+	}
+	// checked before converting to an int, which a count like 1e300 doesn't fit in
+	count := args[0].(float64)
+	if count < 0 {
+		return nil, nativeErrorf("repeat() count can't be negative.")
+	}
+	if count*float64(len(s)) > maxRepeatLength {
+		return nil, nativeErrorf("repeat() can't make a string longer than %d characters.", maxRepeatLength)
+	}
+	if s == "" {
+		return "", nil
+	}
+	return strings.Repeat(string(s), int(count)), nil
+}
+
+// "abc".chars() => ["a", "b", "c"]
+func (s loxString) chars(i interpreter, args []any) (any, error) {
+	elements := make([]any, len(s))
+	for idx := range len(s) {
+		elements[idx] = string(s[idx])
+	}
+	return getLoxList(elements), nil
+}
+
+/*
+"{} has {} items".format(name, 3), each {} is replaced with the next argument. An
+index can be given to pick the argument - "{1} {0}". Use {{ and }} for literal braces.
+The arguments are converted to string like print does.
+*/
+func (s loxString) format(i interpreter, args []any) (any, error) {
+	str := string(s)
+	var sb strings.Builder
+	nextArg := 0
+	for idx := 0; idx < len(str); idx++ {
+		c := str[idx]
+		if (c == '{' || c == '}') && idx+1 < len(str) && str[idx+1] == c {
+			sb.WriteByte(c)
+			idx++
+			continue
+		}
+		if c != '{' {
+			sb.WriteByte(c)
+			continue
+		}
+
+		closing := strings.IndexByte(str[idx:], '}')
+		if closing == -1 {
+			return nil, nativeErrorf("format() has an unclosed '{'.")
+		}
+		placeholder := str[idx+1 : idx+closing]
+		argIndex := nextArg
+		if placeholder != "" {
+			num, err := strconv.Atoi(placeholder)
+			if err != nil {
+				return nil, nativeErrorf("format() placeholder '{%s}' must be empty or an index.", placeholder)
+			}
+			argIndex = num
+		} else {
+			nextArg++
+		}
+		if argIndex < 0 || argIndex >= len(args) {
+			return nil, nativeErrorf("format() doesn't have enough arguments for the placeholders.")
+		}
+		sb.WriteString(getLiteralStr(args[argIndex]))
+		idx += closing
+	}
+	return sb.String(), nil
+}
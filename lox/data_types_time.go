@@ -0,0 +1,292 @@
+package lox
+
+import (
+	"time"
+)
+
+/*
+point in time with its time zone, created through the time module like time.now().
+Printed in the RFC 3339 format.
+*/
+type loxTime struct {
+	t time.Time
+}
+
+var _ dataType = loxTime{}
+
+func (lt loxTime) getMethod(name token) callable {
+	arityCnt, method := lt.getMethodAndArity(name)
+	if method == nil {
+		return nil
+	}
+	return nativeFunction{
+		arityCnt: arityCnt,
+		fn:       method,
+		name:     name.lexeme,
+	}
+}
+
+func (lt loxTime) getMethodAndArity(name token) (int, func(i interpreter, args []any) (any, error)) {
+	switch name.lexeme {
+	case "year":
+		return 0, lt.component(func(t time.Time) int { return t.Year() })
+	case "month": // 1 to 12
+		return 0, lt.component(func(t time.Time) int { return int(t.Month()) })
+	case "day":
+		return 0, lt.component(func(t time.Time) int { return t.Day() })
+	case "hour":
+		return 0, lt.component(func(t time.Time) int { return t.Hour() })
+	case "minute":
+		return 0, lt.component(func(t time.Time) int { return t.Minute() })
+	case "second":
+		return 0, lt.component(func(t time.Time) int { return t.Second() })
+	case "millisecond":
+		return 0, lt.component(func(t time.Time) int { return t.Nanosecond() / int(time.Millisecond) })
+	case "weekday": // 0 for sunday to 6 for saturday
+		return 0, lt.component(func(t time.Time) int { return int(t.Weekday()) })
+	case "yearDay": // 1 to 366
+		return 0, lt.component(func(t time.Time) int { return t.YearDay() })
+	case "unix":
+		return 0, lt.unix
+	case "zone":
+		return 0, lt.zone
+	case "utc":
+		return 0, lt.utc
+	case "inZone":
+		return 1, lt.inZone
+	case "format":
+		return 1, lt.format
+	case "add":
+		return 1, lt.add
+	case "sub":
+		return 1, lt.sub
+	case "before":
+		return 1, lt.compare("before", time.Time.Before)
+	case "after":
+		return 1, lt.compare("after", time.Time.After)
+	case "equal":
+		return 1, lt.compare("equal", time.Time.Equal)
+	default:
+		return 0, nil
+	}
+}
+
+func (lt loxTime) component(get func(t time.Time) int) func(i interpreter, args []any) (any, error) {
+	return func(i interpreter, args []any) (any, error) {
+		return float64(get(lt.t)), nil
+	}
+}
+
+// seconds since the unix epoch, with milliseconds as the fraction
+func (lt loxTime) unix(i interpreter, args []any) (any, error) {
+	return float64(lt.t.UnixMilli()) / 1000, nil
+}
+
+// abbreviated name of the time zone, like "UTC" or "IST"
+func (lt loxTime) zone(i interpreter, args []any) (any, error) {
+	name, _ := lt.t.Zone()
+	return name, nil
+}
+
+func (lt loxTime) utc(i interpreter, args []any) (any, error) {
+	return loxTime{lt.t.UTC()}, nil
+}
+
+// the same time in another zone, like t.inZone("Asia/Kolkata")
+func (lt loxTime) inZone(i interpreter, args []any) (any, error) {
+	name, err := stringArg("inZone", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, nativeErrorf("inZone() unknown time zone '%s'.", name)
+	}
+	return loxTime{lt.t.In(location)}, nil
+}
+
+// layout is either go's reference time format like "2006-01-02" or a name like "RFC3339"
+func (lt loxTime) format(i interpreter, args []any) (any, error) {
+	layout, err := stringArg("format", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return lt.t.Format(timeLayout(layout)), nil
+}
+
+func (lt loxTime) add(i interpreter, args []any) (any, error) {
+	d, err := durationArg("add", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return loxTime{lt.t.Add(d.d)}, nil
+}
+
+// subtracting a time gives the duration between them, subtracting a duration gives a time
+func (lt loxTime) sub(i interpreter, args []any) (any, error) {
+	switch other := args[0].(type) {
+	case loxTime:
+		return loxDuration{lt.t.Sub(other.t)}, nil
+	case loxDuration:
+		return loxTime{lt.t.Add(-other.d)}, nil
+	default:
+		return nil, nativeErrorf("sub() expects argument 1 to be a time or a duration.")
+	}
+}
+
+func (lt loxTime) compare(fnName string, cmp func(t, u time.Time) bool) func(i interpreter, args []any) (any, error) {
+	return func(i interpreter, args []any) (any, error) {
+		other, err := timeArg(fnName, args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return cmp(lt.t, other.t), nil
+	}
+}
+
+func (lt loxTime) String() string {
+	return lt.t.Format(time.RFC3339Nano)
+}
+
+/*
+length of time like 1h30m, created with time.seconds(n) and similar. Printed in
+go's format, for e.g. 1m30.5s
+*/
+type loxDuration struct {
+	d time.Duration
+}
+
+var _ dataType = loxDuration{}
+
+func (ld loxDuration) getMethod(name token) callable {
+	arityCnt, method := ld.getMethodAndArity(name)
+	if method == nil {
+		return nil
+	}
+	return nativeFunction{
+		arityCnt: arityCnt,
+		fn:       method,
+		name:     name.lexeme,
+	}
+}
+
+func (ld loxDuration) getMethodAndArity(name token) (int, func(i interpreter, args []any) (any, error)) {
+	switch name.lexeme {
+	case "hours":
+		return 0, ld.in(time.Hour)
+	case "minutes":
+		return 0, ld.in(time.Minute)
+	case "seconds":
+		return 0, ld.in(time.Second)
+	case "milliseconds":
+		return 0, ld.in(time.Millisecond)
+	case "add":
+		return 1, ld.add
+	case "sub":
+		return 1, ld.sub
+	case "mul":
+		return 1, ld.mul
+	default:
+		return 0, nil
+	}
+}
+
+// the whole duration in the unit, with a fraction - time.minutes(90).hours() is 1.5
+func (ld loxDuration) in(unit time.Duration) func(i interpreter, args []any) (any, error) {
+	return func(i interpreter, args []any) (any, error) {
+		return float64(ld.d) / float64(unit), nil
+	}
+}
+
+func (ld loxDuration) add(i interpreter, args []any) (any, error) {
+	other, err := durationArg("add", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return loxDuration{ld.d + other.d}, nil
+}
+
+func (ld loxDuration) sub(i interpreter, args []any) (any, error) {
+	other, err := durationArg("sub", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return loxDuration{ld.d - other.d}, nil
+}
+
+func (ld loxDuration) mul(i interpreter, args []any) (any, error) {
+	factor, err := numberArg("mul", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return loxDuration{time.Duration(float64(ld.d) * factor)}, nil
+}
+
+func (ld loxDuration) String() string {
+	return ld.d.String()
+}
+
+/*
+monotonic timer for measuring how long some code takes, created with time.timer().
+It uses the host's clock, so it's deterministic with a fake clock.
+*/
+type loxTimer struct {
+	start time.Time
+}
+
+var _ dataType = &loxTimer{}
+
+func (lt *loxTimer) getMethod(name token) callable {
+	switch name.lexeme {
+	case "elapsed":
+		return nativeFunction{name: "elapsed", fn: func(i interpreter, args []any) (any, error) {
+			return loxDuration{clock.Now().Sub(lt.start)}, nil
+		}}
+	case "reset":
+		return nativeFunction{name: "reset", fn: func(i interpreter, args []any) (any, error) {
+			lt.start = clock.Now()
+			return nil, nil
+		}}
+	default:
+		return nil
+	}
+}
+
+func (lt *loxTimer) String() string {
+	return "<timer>"
+}
+
+func timeArg(fnName string, args []any, position int) (loxTime, error) {
+	t, ok := args[position].(loxTime)
+	if !ok {
+		return loxTime{}, nativeErrorf("%s() expects argument %d to be a time.", fnName, position+1)
+	}
+	return t, nil
+}
+
+func durationArg(fnName string, args []any, position int) (loxDuration, error) {
+	d, ok := args[position].(loxDuration)
+	if !ok {
+		return loxDuration{}, nativeErrorf("%s() expects argument %d to be a duration.", fnName, position+1)
+	}
+	return d, nil
+}
+
+var namedTimeLayouts = map[string]string{
+	"ANSIC":    time.ANSIC,
+	"UnixDate": time.UnixDate,
+	"RFC822":   time.RFC822,
+	"RFC1123":  time.RFC1123,
+	"RFC3339":  time.RFC3339,
+	"Kitchen":  time.Kitchen,
+	"DateTime": time.DateTime,
+	"DateOnly": time.DateOnly,
+	"TimeOnly": time.TimeOnly,
+}
+
+func timeLayout(layout string) string {
+	if named, ok := namedTimeLayouts[layout]; ok {
+		return named
+	}
+	return layout
+}
@@ -0,0 +1,358 @@
+package lox
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+/*
+Debugger runs a program while letting the host pause it at breakpoints or step
+through it statement by statement. It's built on a hook the interpreter calls
+before executing each statement, and on every call to and return from a lox
+function to keep track of the call stack.
+
+The program runs on the goroutine calling Run, when it pauses the host's Paused
+is called on the same goroutine, and the program continues once it returns.
+*/
+type Debugger struct {
+	host DebugHost
+
+	mu          sync.Mutex
+	breakpoints map[int]bool
+
+	frames    []debugFrame // innermost last, while running
+	atEntry   bool         // pause before the first statement
+	mode      DebugAction
+	stepDepth int // count of frames when the step was asked for
+}
+
+type debugFrame struct {
+	name   string
+	line   int
+	column int          // of the statement being executed, to tell apart statements on the same line
+	env    *environment // scope of the statement being executed
+}
+
+// decides how to continue after the program pauses
+type DebugAction int
+
+const (
+	DebugContinue  DebugAction = iota // run till the next breakpoint
+	DebugStepInto                     // pause at the next statement, going inside function calls
+	DebugStepOver                     // pause at the next statement in the same function or its callers
+	DebugStepOut                      // pause once the current function returns
+	DebugTerminate                    // stop the program
+)
+
+type DebugHost interface {
+	// reason is "entry", "breakpoint" or "step"
+	Paused(session *DebugSession, reason string) DebugAction
+}
+
+type StackFrame struct {
+	Name   string // name of the function, "<script>" for the top level code
+	Line   int    // where the statement being executed in the frame starts
+	Column int
+}
+
+type Scope struct {
+	Name      string // "locals", "enclosing" or "globals"
+	Variables []Variable
+}
+
+type Variable struct {
+	Name  string
+	Value string // as it's shown by print, with strings quoted
+}
+
+func NewDebugger(host DebugHost) *Debugger {
+	return &Debugger{host: host, breakpoints: make(map[int]bool)}
+}
+
+// replaces all the breakpoints, can be called while the program is running
+func (d *Debugger) SetBreakpoints(lines []int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints = make(map[int]bool)
+	for _, line := range lines {
+		d.breakpoints[line] = true
+	}
+}
+
+/*
+runs the program like Run. With stopOnEntry, it pauses before the first statement
+so breakpoints can be set.
+*/
+func (d *Debugger) Run(code []byte, ctx context.Context, stopOnEntry bool) int {
+	d.frames = []debugFrame{{name: "<script>"}}
+	d.atEntry = stopOnEntry
+	d.mode = DebugContinue
+	return run(code, ctx, d)
+}
+
+func (d *Debugger) hasBreakpoint(line int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.breakpoints[line]
+}
+
+// called by the interpreter before executing each statement
+func (d *Debugger) beforeStatement(i interpreter, st stmt) {
+	if i.ctx.Err() != nil { // stopped by the host, which may not wait for a loop to check it
+		panic(exitRequest{code: 0})
+	}
+	start, ok := stmtStart(st)
+	if !ok {
+		return
+	}
+	frame := &d.frames[len(d.frames)-1]
+	// more statements on the same line like `if (a) print a;` pause only once. Going
+	// back to an earlier statement on the line is the next iteration of a loop.
+	isNewLine := frame.line != start.line || start.startColumn() <= frame.column
+	frame.line, frame.column = start.line, start.startColumn()
+	frame.env = i.env
+	if !isNewLine {
+		return
+	}
+
+	depth := len(d.frames)
+	reason := ""
+	switch {
+	case d.atEntry:
+		reason = "entry"
+		d.atEntry = false
+	case d.mode == DebugStepInto,
+		d.mode == DebugStepOver && depth <= d.stepDepth,
+		d.mode == DebugStepOut && depth < d.stepDepth:
+		reason = "step"
+	case d.hasBreakpoint(start.line):
+		reason = "breakpoint"
+	default:
+		return
+	}
+
+	action := d.host.Paused(&DebugSession{debugger: d, i: i}, reason)
+	if action == DebugTerminate {
+		panic(exitRequest{code: 0})
+	}
+	d.mode = action
+	d.stepDepth = depth
+}
+
+func (d *Debugger) enterFunction(name string) {
+	d.frames = append(d.frames, debugFrame{name: name})
+}
+
+func (d *Debugger) exitFunction() {
+	d.frames = d.frames[:len(d.frames)-1]
+}
+
+/*
+state of the paused program given to the host, it's only valid till Paused returns.
+*/
+type DebugSession struct {
+	debugger *Debugger
+	i        interpreter
+}
+
+func (s *DebugSession) Line() int {
+	return s.debugger.frames[len(s.debugger.frames)-1].line
+}
+
+// innermost frame first
+func (s *DebugSession) CallStack() []StackFrame {
+	stack := make([]StackFrame, len(s.debugger.frames))
+	for idx, frame := range s.debugger.frames {
+		stack[len(stack)-1-idx] = StackFrame{Name: frame.name, Line: frame.line, Column: frame.column}
+	}
+	return stack
+}
+
+/*
+variables visible from the current statement of the frame(0 is the innermost like
+in CallStack), going from the innermost scope to the globals. Closed over variables
+and `this` show up in the enclosing scopes. Native functions and constants are left out
+of the globals.
+*/
+func (s *DebugSession) Scopes(frame int) []Scope {
+	var scopes []Scope
+	frameEnv := s.frameEnv(frame)
+	for env := frameEnv; env != nil; env = env.outer {
+		name := "enclosing"
+		if env == s.i.globals {
+			name = "globals"
+		} else if env == frameEnv {
+			name = "locals"
+		}
+		scope := Scope{Name: name, Variables: []Variable{}}
+		for varName, value := range env.vars {
+			if env == s.i.globals && isNativeGlobal(varName, value) {
+				continue
+			}
+			scope.Variables = append(scope.Variables, Variable{Name: varName, Value: debugValueStr(value)})
+		}
+		slices.SortFunc(scope.Variables, func(a, b Variable) int {
+			return cmp.Compare(a.Name, b.Name)
+		})
+		scopes = append(scopes, scope)
+	}
+	return scopes
+}
+
+/*
+evaluates an expression like a watch in the scope of the frame. Errors while parsing
+or running it are returned instead of stopping the program. The expression can
+call functions and assign variables, but it's not debugged itself.
+*/
+func (s *DebugSession) Evaluate(expression string, frame int) (result string, err error) {
+	errMsg := withCapturedErrors(func() {
+		tokens := tokenize([]byte(expression))
+		if hasParseError {
+			return
+		}
+		parser := newParser[expr](tokens)
+		parsedExpr := parser.parseExpression()
+		if hasParseError {
+			return
+		}
+		if !parser.isAtEnd() {
+			logParseError(parser.tokens[parser.curr], "Expect end of expression.")
+			return
+		}
+
+		i := s.i
+		i.env = s.frameEnv(frame)
+		i.debugger = nil
+		if !s.resolveWatch(&i, parsedExpr) {
+			return
+		}
+		value, _ := i.evaluate(parsedExpr)
+		result = debugValueStr(value)
+	})
+	if errMsg != "" {
+		return "", fmt.Errorf("%s", errMsg)
+	}
+	return result, nil
+}
+
+/*
+resolves the names of the watch against the scopes of the environment it's evaluated
+in, as if it was written there. The depths are added to a copy of the program's, so
+functions called by the watch still find their own variables.
+*/
+func (s *DebugSession) resolveWatch(i *interpreter, watch expr) bool {
+	var scopes []map[string]bool
+	for env := i.env; env != i.globals; env = env.outer {
+		scope := make(map[string]bool)
+		for name := range env.vars {
+			scope[name] = true
+		}
+		scopes = append([]map[string]bool{scope}, scopes...)
+	}
+	i.locals = maps.Clone(i.locals)
+	r := newResolver(i)
+	r.scopes = scopes
+	for _, scope := range scopes {
+		if scope["super"] {
+			r.currClass = cSubClass
+		} else if scope["this"] && r.currClass == cNone {
+			r.currClass = cClass
+		}
+	}
+	if _, err := r.resolveExpr(watch); err != nil {
+		if pErr, ok := err.(*parseError); ok {
+			logResolveError(pErr.token, pErr.msg)
+		} else {
+			logResolveError(token{}, err.Error())
+		}
+		return false
+	}
+	return !hasParseError
+}
+
+// frames out of range give the globals
+func (s *DebugSession) frameEnv(frame int) *environment {
+	frames := s.debugger.frames
+	if frame < 0 || frame >= len(frames) {
+		return s.i.globals
+	}
+	return frames[len(frames)-1-frame].env
+}
+
+/*
+runs fn with the errors logged by it captured instead of going to the host, and
+without affecting the error state of the program. Gives the first error message.
+*/
+func withCapturedErrors(fn func()) (errMsg string) {
+	capture := func(msg string) {
+		if errMsg == "" {
+			errMsg = msg
+		}
+	}
+	// the rest like print still goes to the host
+	captured := logger
+	captured.ScanError = func(line int, col int, msg string) { capture(msg) }
+	captured.ParseError = func(token TokenLogMeta, msg string) { capture(msg) }
+	captured.ResolveError = nil // reported as parse errors
+	captured.RuntimeError = func(token TokenLogMeta, msg string) { capture(msg) }
+	withLogger(captured, func() {
+		defer func() {
+			r := recover()
+			if _, ok := r.(exitRequest); ok {
+				capture("exit() can't be called while debugging an expression.")
+			} else if r != nil {
+				capture(fmt.Sprint(r))
+			}
+		}()
+		fn()
+	})
+	return errMsg
+}
+
+// PI and E are left out too, unless the program has assigned something else to them
+func isNativeGlobal(name string, value any) bool {
+	switch value.(type) {
+	case nativeFunction, timeModule:
+		return true
+	default:
+		return name == "PI" && value == math.Pi || name == "E" && value == math.E
+	}
+}
+
+func debugValueStr(value any) string {
+	if str, ok := value.(string); ok {
+		return strconv.Quote(str)
+	}
+	return getLiteralStr(value)
+}
+
+// token where the statement starts, blocks and for loops don't have one as they're never
+// paused at, a for loop pauses at the statements it's desugared to
+func stmtStart(st stmt) (token, bool) {
+	switch st := st.(type) {
+	case sExpr:
+		return st.start, true
+	case sPrint:
+		return st.keyword, true
+	case sVar:
+		return st.keyword, true
+	case sIf:
+		return st.keyword, true
+	case sWhile:
+		return st.keyword, true
+	case sFunction:
+		return st.name, true
+	case sReturn:
+		return st.keyword, true
+	case sClass:
+		return st.name, true
+	default:
+		return token{}, false
+	}
+}
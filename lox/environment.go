@@ -53,6 +53,17 @@ func (e *environment) set(name string, val any) error {
 	}
 }
 
+// of the variables in this scope and the enclosing ones
+func (e *environment) names() []string {
+	var names []string
+	for env := e; env != nil; env = env.outer {
+		for name := range env.vars {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 func (e *environment) getAt(depth int, name string) (any, error) {
 	return e.ancestor(depth).get(name)
 }
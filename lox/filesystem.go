@@ -0,0 +1,153 @@
+package lox
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+/*
+File system used by the file natives like readFile. It's set by the host, the cli
+uses the real OS file system, while in wasm there is no disk so files are kept in memory.
+*/
+type FileSystem interface {
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte) error
+	AppendFile(name string, data []byte) error
+	Exists(name string) bool
+	ListDir(name string) ([]string, error) // sorted names of the entries in the directory
+	Remove(name string) error
+}
+
+var fileSystem FileSystem
+
+func SetFileSystem(fileSystem2 FileSystem) {
+	fileSystem = fileSystem2
+}
+
+type osFileSystem struct{}
+
+func NewOSFileSystem() FileSystem {
+	return osFileSystem{}
+}
+
+func (osFileSystem) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (osFileSystem) WriteFile(name string, data []byte) error {
+	return os.WriteFile(name, data, 0644)
+}
+
+func (osFileSystem) AppendFile(name string, data []byte) error {
+	file, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(data)
+	return err
+}
+
+func (osFileSystem) Exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+func (osFileSystem) ListDir(name string) ([]string, error) {
+	entries, err := os.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(entries))
+	for idx, entry := range entries {
+		names[idx] = entry.Name()
+	}
+	return names, nil
+}
+
+func (osFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+/*
+keeps the files in a map from the cleaned path to its content. Directories aren't
+stored, they exist as long as there is a file inside them.
+*/
+type memoryFileSystem struct {
+	files map[string][]byte
+}
+
+func NewMemoryFileSystem() FileSystem {
+	return &memoryFileSystem{files: make(map[string][]byte)}
+}
+
+func (m *memoryFileSystem) ReadFile(name string) ([]byte, error) {
+	data, ok := m.files[path.Clean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return slices.Clone(data), nil
+}
+
+func (m *memoryFileSystem) WriteFile(name string, data []byte) error {
+	m.files[path.Clean(name)] = slices.Clone(data)
+	return nil
+}
+
+func (m *memoryFileSystem) AppendFile(name string, data []byte) error {
+	name = path.Clean(name)
+	m.files[name] = append(m.files[name], data...)
+	return nil
+}
+
+func (m *memoryFileSystem) Exists(name string) bool {
+	name = path.Clean(name)
+	if _, ok := m.files[name]; ok {
+		return true
+	}
+	return name == "." || len(m.entriesIn(name)) > 0
+}
+
+func (m *memoryFileSystem) ListDir(name string) ([]string, error) {
+	name = path.Clean(name)
+	entries := m.entriesIn(name)
+	if len(entries) == 0 && name != "." {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return entries, nil
+}
+
+func (m *memoryFileSystem) Remove(name string) error {
+	name = path.Clean(name)
+	if _, ok := m.files[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(m.files, name)
+	return nil
+}
+
+// names of files and sub directories directly inside the directory
+func (m *memoryFileSystem) entriesIn(dir string) []string {
+	prefix := dir + "/"
+	if dir == "." {
+		prefix = ""
+	} else if dir == "/" {
+		prefix = dir
+	}
+	var entries []string
+	for file := range m.files {
+		rest, ok := strings.CutPrefix(file, prefix)
+		if !ok || rest == "" {
+			continue
+		}
+		entry, _, _ := strings.Cut(rest, "/")
+		if !slices.Contains(entries, entry) {
+			entries = append(entries, entry)
+		}
+	}
+	slices.Sort(entries)
+	return entries
+}
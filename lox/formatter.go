@@ -0,0 +1,497 @@
+package lox
+
+import (
+	"strings"
+)
+
+const formatIndent = "  "
+const maxLineWidth = 100
+
+/*
+Format gives the code in the canonical style - two spaces for indentation, spaces
+around binary operators, "{" on the line of the statement it belongs to, and at
+most one blank line between statements. Lists and call arguments which don't fit
+on a line are put one per line. Comments are kept on their own lines, or at the
+end of the line they were on. When the code has errors, they're logged and the
+exit code for them is returned.
+*/
+func Format(code []byte) ([]byte, int) {
+	tokens := tokenize(code)
+	if hasParseError {
+		return nil, compileErrorExitCode
+	}
+	statements := newParser[expr](tokens).parse()
+	if hasParseError {
+		return nil, compileErrorExitCode
+	}
+
+	f := &formatter{blockStart: true}
+	for _, t := range tokens {
+		if t.trivia != nil {
+			f.carriers = append(f.carriers, t)
+		}
+	}
+	f.stmts(statements)
+	f.leading(tokens[len(tokens)-1])
+	if len(f.lines) == 0 {
+		return []byte{}, 0
+	}
+	return []byte(strings.Join(f.lines, "\n") + "\n"), 0
+}
+
+/*
+prints the program line by line. Comments are printed when the formatter gets
+to the token they're attached to, so they stay in the same order. The ones inside
+an expression, which would break it, are moved to the end of the statement's line.
+*/
+type formatter struct {
+	lines      []string
+	indent     int
+	carriers   []token  // tokens with trivia which isn't printed yet, in the order of the code
+	pending    []string // comments from inside the statement being printed, for the end of its line
+	wantBlank  bool     // there was a blank line in the code before what's printed next
+	blockStart bool     // nothing is printed in the block yet, so it doesn't start with a blank line
+	joinNext   bool     // the next line continues the last one, for "} else {"
+}
+
+func (f *formatter) emit(text string) {
+	if len(f.pending) > 0 {
+		text += " " + strings.Join(f.pending, " ")
+		f.pending = nil
+	}
+	if f.joinNext {
+		f.lines[len(f.lines)-1] += " " + text
+		f.joinNext = false
+		return
+	}
+	if f.wantBlank && !f.blockStart {
+		f.lines = append(f.lines, "")
+	}
+	f.wantBlank, f.blockStart = false, false
+	f.lines = append(f.lines, strings.Repeat(formatIndent, f.indent)+text)
+}
+
+// pops the trivia of the tokens till t, which is the last one if t is the end of the file
+func (f *formatter) triviaTill(t token) []*trivia {
+	var result []*trivia
+	for len(f.carriers) > 0 {
+		carrier := f.carriers[0]
+		if carrier.offset > t.offset && t.tokenType != tEof {
+			break
+		}
+		result = append(result, carrier.trivia)
+		f.carriers = f.carriers[1:]
+	}
+	return result
+}
+
+func (f *formatter) hasCommentsTill(t token) bool {
+	for _, carrier := range f.carriers {
+		if carrier.offset > t.offset {
+			return false
+		}
+		if len(carrier.trivia.comments) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// prints the comments before a statement or the end of a block
+func (f *formatter) leading(t token) {
+	for _, trivia := range f.triviaTill(t) {
+		for _, c := range trivia.comments {
+			text := strings.TrimRight(c.text, " \t\r")
+			if !c.ownLine && len(f.lines) > 0 {
+				f.lines[len(f.lines)-1] += " " + text
+				continue
+			}
+			if c.blankLineBefore {
+				f.wantBlank = true
+			}
+			f.emit(text)
+		}
+		if trivia.blankLineBefore {
+			f.wantBlank = true
+		}
+	}
+}
+
+// comments before a token inside a statement go to the end of its line
+func (f *formatter) inner(t token) {
+	for _, trivia := range f.triviaTill(t) {
+		for _, c := range trivia.comments {
+			f.pending = append(f.pending, strings.TrimRight(c.text, " \t\r"))
+		}
+	}
+}
+
+func (f *formatter) stmts(statements []stmt) {
+	for _, st := range statements {
+		f.stmt(st)
+	}
+}
+
+func (f *formatter) stmt(st stmt) {
+	switch st := st.(type) {
+	case sExpr:
+		f.leading(st.start)
+		f.simple("", st.expression, ";")
+	case sPrint:
+		f.leading(st.keyword)
+		f.simple("print ", st.expression, ";")
+	case sVar:
+		f.leading(st.keyword)
+		f.inner(st.name)
+		if st.initializer == nil {
+			f.emit("var " + st.name.lexeme + ";")
+		} else {
+			f.simple("var "+st.name.lexeme+" = ", st.initializer, ";")
+		}
+	case sReturn:
+		f.leading(st.keyword)
+		if st.value == nil {
+			f.emit("return;")
+		} else {
+			f.simple("return ", st.value, ";")
+		}
+	case sBlock:
+		f.leading(st.start)
+		f.block("", st.statements, st.end)
+	case sIf:
+		f.leading(st.keyword)
+		f.ifStmt(st, "")
+	case sWhile:
+		f.leading(st.keyword)
+		f.body("while ("+f.flat(st.condition)+") ", st.body)
+	case sFor:
+		f.leading(st.keyword)
+		f.forStmt(st)
+	case sFunction:
+		f.leading(st.name)
+		f.function("fun ", st)
+	case sClass:
+		f.leading(st.name)
+		f.class(st)
+	}
+}
+
+// statements which are a single expression with something before and after it
+func (f *formatter) simple(prefix string, e expr, suffix string) {
+	col := len(formatIndent)*f.indent + len(prefix)
+	f.emit(prefix + f.format(e, col, f.indent) + suffix)
+}
+
+// prints the header followed by "{", the statements and "}", or "{}" when there's nothing inside
+func (f *formatter) block(header string, statements []stmt, end token) {
+	if len(statements) == 0 && !f.hasCommentsTill(end) {
+		f.triviaTill(end)
+		f.emit(header + "{}")
+		return
+	}
+	f.emit(header + "{")
+	f.indent++
+	f.blockStart = true
+	f.stmts(statements)
+	f.leading(end)
+	f.wantBlank = false // no blank line before the "}"
+	f.indent--
+	f.emit("}")
+}
+
+func (f *formatter) ifStmt(st sIf, prefix string) {
+	f.inner(st.keyword)
+	f.body(prefix+"if ("+f.flat(st.condition)+") ", st.thenBranch)
+	if st.elseBranch == nil {
+		return
+	}
+	// comments before the "else" stay after the then branch, "} else {" is only joined without them
+	hasComments := f.hasCommentsTill(st.elseToken)
+	f.leading(st.elseToken)
+	f.wantBlank = false
+	_, isBlock := st.thenBranch.(sBlock)
+	f.joinNext = isBlock && !hasComments
+	if elseIf, ok := st.elseBranch.(sIf); ok {
+		f.ifStmt(elseIf, "else ")
+	} else {
+		f.body("else ", st.elseBranch)
+	}
+}
+
+func (f *formatter) forStmt(st sFor) {
+	header := "for ("
+	switch initializer := st.initializer.(type) {
+	case sVar:
+		f.inner(initializer.name)
+		header += "var " + initializer.name.lexeme
+		if initializer.initializer != nil {
+			header += " = " + f.flat(initializer.initializer)
+		}
+	case sExpr:
+		header += f.flat(initializer.expression)
+	}
+	header += ";"
+	if st.condition != nil {
+		header += " " + f.flat(st.condition)
+	}
+	header += ";"
+	if st.updater != nil {
+		header += " " + f.flat(st.updater)
+	}
+	f.body(header+") ", st.body)
+}
+
+/*
+the body of if, while and for loops. A block starts on the line of the header,
+other statements are on the same line too if they fit, otherwise on the next line
+indented.
+*/
+func (f *formatter) body(header string, body stmt) {
+	if block, ok := body.(sBlock); ok {
+		f.inner(block.start)
+		f.block(header, block.statements, block.end)
+		return
+	}
+
+	// the statement is printed separately to see if it fits on a line
+	orig := *f
+	f.lines, f.indent, f.wantBlank, f.blockStart, f.joinNext = nil, 0, false, true, false
+	f.stmt(body)
+	bodyLines := f.lines
+	f.lines, f.indent, f.wantBlank, f.blockStart, f.joinNext = orig.lines, orig.indent, orig.wantBlank, orig.blockStart, orig.joinNext
+
+	if len(bodyLines) == 1 && len(formatIndent)*f.indent+len(header)+len(bodyLines[0]) <= maxLineWidth {
+		f.emit(header + bodyLines[0])
+		return
+	}
+	f.emit(strings.TrimSuffix(header, " "))
+	for _, line := range bodyLines {
+		if line != "" {
+			line = strings.Repeat(formatIndent, f.indent+1) + line
+		}
+		f.lines = append(f.lines, line)
+	}
+}
+
+func (f *formatter) function(prefix string, function sFunction) {
+	params := make([]string, len(function.parameters))
+	for idx, param := range function.parameters {
+		f.inner(param)
+		params[idx] = param.lexeme
+	}
+	f.block(prefix+function.name.lexeme+"("+strings.Join(params, ", ")+") ", function.body, function.end)
+}
+
+func (f *formatter) class(class sClass) {
+	header := "class " + class.name.lexeme + " "
+	if class.superclass != nil {
+		f.inner(class.superclass.name)
+		header += "< " + class.superclass.name.lexeme + " "
+	}
+	if len(class.methods) == 0 && !f.hasCommentsTill(class.end) {
+		f.triviaTill(class.end)
+		f.emit(header + "{}")
+		return
+	}
+	f.emit(header + "{")
+	f.indent++
+	f.blockStart = true
+	for _, method := range class.methods {
+		f.leading(method.name)
+		f.function("", method)
+	}
+	f.leading(class.end)
+	f.wantBlank = false
+	f.indent--
+	f.emit("}")
+}
+
+/*
+the expression starting at the column col of a line indented depth times. Lists and
+call arguments are broken one per line if they don't fit.
+*/
+func (f *formatter) format(e expr, col int, depth int) string {
+	flat := f.flat(e)
+	if col+len(flat) <= maxLineWidth {
+		return flat
+	}
+	switch e := e.(type) {
+	case eCall:
+		return f.format(e.callee, col, depth) + "(" + f.brokenList(e.arguments, depth, false) + ")"
+	case eList:
+		return "[" + f.brokenList(e.elements, depth, true) + "]"
+	case eBinary:
+		return f.brokenBinary(e.left, e.operator, e.right, col, depth)
+	case eLogical:
+		return f.brokenBinary(e.left, e.operator, e.right, col, depth)
+	case eGrouping:
+		return "(" + f.format(e.expression, col+1, depth) + ")"
+	case eAssign:
+		if e.value != nil && !isIncrement(e.operator) {
+			prefix := e.name.lexeme + " " + e.operator.lexeme + " "
+			return prefix + f.format(e.value, col+len(prefix), depth)
+		}
+	case eGet:
+		return f.format(e.object, col, depth) + "." + e.name.lexeme
+	}
+	return flat
+}
+
+// the right side continues where the left one ends, which can be on a later line
+func (f *formatter) brokenBinary(left expr, operator token, right expr, col int, depth int) string {
+	leftStr := f.format(left, col, depth)
+	leftEnd := col + len(leftStr)
+	if idx := strings.LastIndex(leftStr, "\n"); idx != -1 {
+		leftEnd = len(leftStr) - idx - 1
+	}
+	middle := " " + operator.lexeme + " "
+	return leftStr + middle + f.format(right, leftEnd+len(middle), depth)
+}
+
+// calls can't have a comma after the last argument, lists can
+func (f *formatter) brokenList(elements []expr, depth int, trailingComma bool) string {
+	if len(elements) == 0 {
+		return ""
+	}
+	indent := strings.Repeat(formatIndent, depth+1)
+	var sb strings.Builder
+	for idx, element := range elements {
+		sb.WriteString("\n" + indent + f.format(element, len(indent), depth+1))
+		if idx < len(elements)-1 || trailingComma {
+			sb.WriteString(",")
+		}
+	}
+	sb.WriteString("\n" + strings.Repeat(formatIndent, depth))
+	return sb.String()
+}
+
+// the expression on a single line
+func (f *formatter) flat(e expr) string {
+	switch e := e.(type) {
+	case eLiteral:
+		if e.token.tokenType == tString {
+			return "\"" + stringSource(e.token) + "\""
+		}
+		return getLiteralStr(e.value)
+	case eVariable:
+		f.inner(e.name)
+		return e.name.lexeme
+	case eThis:
+		f.inner(e.keyword)
+		return "this"
+	case eSuper:
+		f.inner(e.method)
+		return "super." + e.method.lexeme
+	case eGrouping:
+		return "(" + f.flat(e.expression) + ")"
+	case eUnary:
+		right := f.flat(e.right)
+		f.inner(e.operator)
+		if e.operator.lexeme == "-" && strings.HasPrefix(right, "-") { // "- -a" isn't "--a"
+			return "- " + right
+		}
+		return e.operator.lexeme + right
+	case eBinary:
+		left := f.flat(e.left)
+		f.inner(e.operator)
+		return left + " " + e.operator.lexeme + " " + f.flat(e.right)
+	case eLogical:
+		left := f.flat(e.left)
+		f.inner(e.operator)
+		return left + " " + e.operator.lexeme + " " + f.flat(e.right)
+	case eCall:
+		callee := f.flat(e.callee)
+		args := f.flatList(e.arguments)
+		f.inner(e.paren)
+		return callee + "(" + args + ")"
+	case eGet:
+		object := f.flat(e.object)
+		f.inner(e.name)
+		return object + "." + e.name.lexeme
+	case eGetIndex:
+		object, key := f.flat(e.object), f.flat(e.key)
+		f.inner(e.bracket)
+		return object + "[" + key + "]"
+	case eSlice:
+		var start, end string
+		if e.start != nil {
+			start = f.flat(e.start)
+		}
+		f.inner(e.colon)
+		if e.end != nil {
+			end = f.flat(e.end)
+		}
+		if e.step != nil {
+			return start + ":" + end + ":" + f.flat(e.step)
+		}
+		return start + ":" + end
+	case eAssign:
+		f.inner(e.name)
+		return f.assignment(e.name.lexeme, e.operator, e.value, e.postfix)
+	case eSet:
+		target := f.flat(e.object) + "." + e.name.lexeme
+		f.inner(e.name)
+		return f.assignment(target, e.operator, e.value, e.postfix)
+	case eSetIndex:
+		target := f.flat(e.object) + "[" + f.flat(e.key) + "]"
+		f.inner(e.bracket)
+		return f.assignment(target, e.operator, e.value, e.postfix)
+	case eList:
+		return "[" + f.flatList(e.elements) + "]"
+	case eTemplate:
+		var sb strings.Builder
+		sb.WriteString("\"")
+		for _, part := range e.parts {
+			if literal, ok := part.(eLiteral); ok && isTemplateText(literal.token) {
+				sb.WriteString(stringSource(literal.token))
+				continue
+			}
+			sb.WriteString("${" + f.flat(part) + "}")
+		}
+		sb.WriteString("\"")
+		return sb.String()
+	default:
+		return ""
+	}
+}
+
+/*
+the text of the string or the part of a template as it's written, so escapes like
+"\${" are kept. The lexeme is with the quotes, or the "}" and "${" around the part.
+*/
+func stringSource(t token) string {
+	text := t.lexeme[1:]
+	if t.tokenType == tInterpolation {
+		return strings.TrimSuffix(text, "${")
+	}
+	return strings.TrimSuffix(text, "\"")
+}
+
+// the parts of the template itself, rather than a string embedded in it like "${"a"}"
+func isTemplateText(t token) bool {
+	return t.tokenType == tInterpolation || (t.tokenType == tString && strings.HasPrefix(t.lexeme, "}"))
+}
+
+func (f *formatter) flatList(elements []expr) string {
+	strs := make([]string, len(elements))
+	for idx, element := range elements {
+		strs[idx] = f.flat(element)
+	}
+	return strings.Join(strs, ", ")
+}
+
+// a = b, a += b, a++ or ++a
+func (f *formatter) assignment(target string, operator token, value expr, postfix bool) string {
+	f.inner(operator)
+	if isIncrement(operator) {
+		if postfix {
+			return target + operator.lexeme
+		}
+		return operator.lexeme + target
+	}
+	return target + " " + operator.lexeme + " " + f.flat(value)
+}
+
+func isIncrement(operator token) bool {
+	return operator.tokenType == tPlusPlus || operator.tokenType == tMinusMinus
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"strings"
 )
 
 /*
@@ -16,11 +17,62 @@ type interpreter struct {
 	globals *environment  // permanent reference to the global environment
 	env     *environment  // reference to the environment of the current scope/block
 	locals  map[token]int // store the scope depth for each variable token usage
+
+	debugger *Debugger // nil unless the program is being debugged
 }
 
 var _ exprVisitor = (*interpreter)(nil)
 var _ stmtVisitor = (*interpreter)(nil)
 
+/*
+calls being made, outermost first, for the stack traces of runtime errors. A runtime
+error panics without popping its calls, so they're all there when it's logged. It's
+global like the error state, as the errors are logged without the interpreter.
+*/
+var callStack []callFrame
+
+type callFrame struct {
+	name string // of the function called
+	call token  // ")" of the call
+}
+
+// the frames for an error at the token, innermost first
+func stackTrace(at token) []StackFrame {
+	stack := make([]StackFrame, 0, len(callStack)+1)
+	for idx := len(callStack) - 1; idx >= 0; idx-- {
+		stack = append(stack, StackFrame{Name: callStack[idx].name, Line: at.line, Column: at.startColumn()})
+		at = callStack[idx].call
+	}
+	return append(stack, StackFrame{Name: "<script>", Line: at.line, Column: at.startColumn()})
+}
+
+func callableName(fn callable) string {
+	switch fn := fn.(type) {
+	case loxFunction:
+		return fn.declaration.name.lexeme
+	case nativeFunction:
+		if fn.name != "" {
+			return fn.name
+		}
+	}
+	return fn.String()
+}
+
+/*
+calls a callback passed to a native function, like the function given to map. It's
+put on the call stack like calls in the code are, called from where the native was.
+*/
+func callCallback(i interpreter, fn callable, args []any) (any, error) {
+	var at token
+	if len(callStack) > 0 {
+		at = callStack[len(callStack)-1].call
+	}
+	callStack = append(callStack, callFrame{name: callableName(fn), call: at})
+	val, err := fn.call(i, args)
+	callStack = callStack[:len(callStack)-1]
+	return val, err
+}
+
 func newInterpreter() *interpreter {
 	globals := newEnvironment()
 	defineNativeFunctions(globals)
@@ -126,6 +178,11 @@ func (i interpreter) visitClassStmt(s sClass) error {
 	return nil
 }
 
+// the desugared loop isn't a statement of its own for the debugger, so it's not executed through execute
+func (i interpreter) visitForStmt(s sFor) error {
+	return s.desugared.accept(i)
+}
+
 func (i interpreter) visitWhileStmt(s sWhile) error {
 	done := i.ctx.Done()
 	for {
@@ -157,6 +214,9 @@ func (i interpreter) executeBlock(statements []stmt, outerEnv *environment) erro
 }
 
 func (i interpreter) execute(stmt stmt) error {
+	if i.debugger != nil {
+		i.debugger.beforeStatement(i, stmt)
+	}
 	return stmt.accept(i)
 }
 
@@ -180,7 +240,9 @@ func (i interpreter) visitVarStmt(s sVar) error {
 a = 123;
 */
 func (i interpreter) visitAssignExpr(e eAssign) (any, error) {
-	val := getJustVal(i.evaluate(e.value))
+	val, result := i.assignmentValue(e.operator, e.postfix, func() any {
+		return getJustVal(i.visitVariableExpr(eVariable{name: e.name}))
+	}, e.value)
 	dist, exists := i.locals[e.name]
 	var err error
 	if exists {
@@ -189,15 +251,44 @@ func (i interpreter) visitAssignExpr(e eAssign) (any, error) {
 		err = i.globals.set(e.name.lexeme, val)
 	}
 	if err != nil {
-		logRuntimeError(e.name, "Undefined variable '"+e.name.lexeme+"'.")
+		logUndefinedError(e.name, "Undefined variable '"+e.name.lexeme+"'.", i.env.names())
 	}
-	return val, nil
+	return result, nil
+}
+
+/*
+gives the value to be stored by an assignment, and the value the assignment expression
+itself evaluates to. For compound assignments(a += 2, a++) the current value is read
+only once via the passed function, before the right side is evaluated.
+*/
+func (i interpreter) assignmentValue(operator token, postfix bool, current func() any, value expr) (any, any) {
+	if operator.tokenType == tEqual {
+		val := getJustVal(i.evaluate(value))
+		return val, val
+	}
+
+	old := current()
+	if operator.tokenType == tPlusPlus || operator.tokenType == tMinusMinus {
+		validateNumberOperand(old, operator)
+	}
+	right := getJustVal(i.evaluate(value))
+	binaryOperator := operator
+	binaryOperator.tokenType = compoundAssignTokens[operator.tokenType]
+	val := getJustVal(evalBinary(old, binaryOperator, right))
+	if postfix {
+		return val, old
+	}
+	return val, val
 }
 
 func (i interpreter) visitBinaryExpr(e eBinary) (any, error) {
 	left := getJustVal(i.evaluate(e.left))
 	right := getJustVal(i.evaluate(e.right))
-	switch e.operator.tokenType {
+	return evalBinary(left, e.operator, right)
+}
+
+func evalBinary(left any, operator token, right any) (any, error) {
+	switch operator.tokenType {
 	case tPlus:
 		if isString(left) || isString(right) {
 			// if either side is string, convert the other side to string as well
@@ -205,37 +296,62 @@ func (i interpreter) visitBinaryExpr(e eBinary) (any, error) {
 		} else if isNumber(left) && isNumber(right) {
 			return left.(float64) + right.(float64), nil
 		} else if isList(left) && isList(right) {
-			return left.(*loxList).concat([]any{right.(*loxList)}), nil
+			return left.(*loxList).concatList(right.(*loxList)), nil
 		} else {
-			logRuntimeError(e.operator, "Operands must be two numbers or two strings.")
+			logRuntimeError(operator, "Operands must be two numbers or two strings.")
 		}
 	case tMinus:
-		validateNumberOperand2(left, right, e.operator)
+		validateNumberOperand2(left, right, operator)
 		return left.(float64) - right.(float64), nil
 	case tStar:
-		validateNumberOperand2(left, right, e.operator)
+		validateNumberOperand2(left, right, operator)
 		return left.(float64) * right.(float64), nil
 	case tMod:
-		validateNumberOperand2(left, right, e.operator)
+		validateNumberOperand2(left, right, operator)
 		return math.Mod(left.(float64), right.(float64)), nil
+	case tStarStar:
+		validateNumberOperand2(left, right, operator)
+		return math.Pow(left.(float64), right.(float64)), nil
 	case tXor:
-		validateNumberOperand2(left, right, e.operator)
-		return float64(int(left.(float64)) ^ int(right.(float64))), nil
+		left, right := integerOperands(left, right, operator)
+		return float64(left ^ right), nil
+	case tBitAnd:
+		left, right := integerOperands(left, right, operator)
+		return float64(left & right), nil
+	case tBitOr:
+		left, right := integerOperands(left, right, operator)
+		return float64(left | right), nil
+	case tShiftLeft, tShiftRight:
+		left, right := integerOperands(left, right, operator)
+		if right < 0 {
+			logRuntimeError(operator, "Shift amount must be non-negative.")
+		}
+		if right >= 64 {
+			logRuntimeError(operator, "Shift amount must be less than 64.")
+		}
+		if operator.tokenType == tShiftLeft {
+			return float64(left << right), nil
+		}
+		return float64(left >> right), nil
 	case tSlash:
-		validateNumberOperand2(left, right, e.operator)
-		validateNonZeroDenom(right.(float64), e.operator)
+		validateNumberOperand2(left, right, operator)
+		validateNonZeroDenom(right.(float64), operator)
 		return left.(float64) / right.(float64), nil
+	case tFloorDiv:
+		validateNumberOperand2(left, right, operator)
+		validateNonZeroDenom(right.(float64), operator)
+		return math.Floor(left.(float64) / right.(float64)), nil
 	case tGreater:
-		validateNumberOperand2(left, right, e.operator)
+		validateNumberOperand2(left, right, operator)
 		return left.(float64) > right.(float64), nil
 	case tGreaterEqual:
-		validateNumberOperand2(left, right, e.operator)
+		validateNumberOperand2(left, right, operator)
 		return left.(float64) >= right.(float64), nil
 	case tLess:
-		validateNumberOperand2(left, right, e.operator)
+		validateNumberOperand2(left, right, operator)
 		return left.(float64) < right.(float64), nil
 	case tLessEqual:
-		validateNumberOperand2(left, right, e.operator)
+		validateNumberOperand2(left, right, operator)
 		return left.(float64) <= right.(float64), nil
 	case tEqualEqual:
 		return checkEqua(left, right), nil
@@ -277,12 +393,17 @@ func (i interpreter) visitCallExpr(e eCall) (any, error) {
 	if !ok {
 		logRuntimeError(e.paren, "Can only call functions and classes.")
 	}
-	if len(args) != callee2.arity() {
+	if callee2.arity() != variadicArity && len(args) != callee2.arity() {
 		logRuntimeError(e.paren,
 			fmt.Sprintf("Expected %d arguments but got %d.", callee2.arity(), len(args)))
 	}
-	// fmt.Printf("calling %v with %v\n", callee2, args)
-	return callee2.call(i, args)
+	callStack = append(callStack, callFrame{name: callableName(callee2), call: e.paren})
+	val, err := callee2.call(i, args)
+	callStack = callStack[:len(callStack)-1]
+	if nErr, ok := err.(nativeError); ok {
+		logRuntimeError(e.paren, nErr.msg)
+	}
+	return val, err
 }
 
 // creating a list - [1,2,3]
@@ -298,26 +419,90 @@ func (i interpreter) visitListExpr(e eList) (any, error) {
 	return list, nil
 }
 
-// accessing array index
+/*
+string interpolation - "Hello ${name}". Each part is converted to string the same
+way print does it.
+*/
+func (i interpreter) visitTemplateExpr(e eTemplate) (any, error) {
+	var sb strings.Builder
+	for _, part := range e.parts {
+		val, err := i.evaluate(part)
+		if err != nil {
+			return nil, err
+		}
+		sb.WriteString(getLiteralStr(val))
+	}
+	return sb.String(), nil
+}
+
+// evaluates start:end:step of a slice, the parts must be integers or nil
+func (i interpreter) visitSliceExpr(e eSlice) (any, error) {
+	var r sliceRange
+	parts := []expr{e.start, e.end, e.step}
+	values := []**int{&r.start, &r.end, &r.step}
+	for idx, part := range parts {
+		if part == nil {
+			continue
+		}
+		val, err := i.evaluate(part)
+		if err != nil {
+			return nil, err
+		}
+		if val == nil {
+			continue
+		}
+		if !isInteger(val) {
+			logRuntimeError(e.colon, "Slice indices must be integers or nil.")
+		}
+		num := int(val.(float64))
+		*values[idx] = &num
+	}
+	if r.step != nil && *r.step == 0 {
+		logRuntimeError(e.colon, "Slice step cannot be zero.")
+	}
+	return r, nil
+}
+
+// accessing array index or slice
 func (i interpreter) visitGetIndexExpr(e eGetIndex) (any, error) {
 	obj, err := i.evaluate(e.object)
 	if err != nil {
 		return nil, err
 	}
-	indexFloat, err := i.evaluate(e.key)
+	key, err := i.evaluate(e.key)
 	if err != nil {
 		return nil, err
 	}
-	index := int(indexFloat.(float64))
+
+	if r, ok := key.(sliceRange); ok {
+		switch obj2 := obj.(type) {
+		case *loxList:
+			return obj2.getSlice(r), nil
+		case string:
+			return sliceString(obj2, r), nil
+		default:
+			logRuntimeError(e.bracket, "Only lists and strings can be sliced.")
+			return nil, errors.New("unreachable")
+		}
+	}
+
+	if m, ok := obj.(*loxMap); ok {
+		value, ok := m.get(mapKey(key, e.bracket))
+		if !ok {
+			logRuntimeError(e.bracket, fmt.Sprintf("Undefined key '%s'.", getLiteralStr(key)))
+		}
+		return value, nil
+	}
 
 	switch obj2 := obj.(type) {
 	case *loxList:
-		return obj2.getAtIndex(index), nil
+		return obj2.getAtIndex(indexValue(key, e.bracket)), nil
 	case string:
+		index := indexValue(key, e.bracket)
 		if index < 0 {
 			index = len(obj2) + index
 		}
-		if index >= len(obj2) {
+		if index < 0 || index >= len(obj2) {
 			logRuntimeError(e.bracket, "Index out of bounds")
 			return nil, errors.New("unreachable")
 		}
@@ -328,30 +513,65 @@ func (i interpreter) visitGetIndexExpr(e eGetIndex) (any, error) {
 	}
 }
 
-// setting array index
+// the index of a list or string, whether it's in range is checked where it's used
+func indexValue(key any, bracket token) int {
+	num, ok := key.(float64)
+	if !ok {
+		logRuntimeError(bracket, "Index must be a number.")
+	}
+	return int(num)
+}
+
+// setting array index or slice
 func (i interpreter) visitSetIndexExpr(e eSetIndex) (any, error) {
 	obj, err := i.evaluate(e.object)
 	if err != nil {
 		return nil, err
 	}
-	indexFloat, err := i.evaluate(e.key)
+	key, err := i.evaluate(e.key)
 	if err != nil {
 		return nil, err
 	}
-	index := int(indexFloat.(float64))
 
-	value, err := i.evaluate(e.value)
-	if err != nil {
-		return nil, err
+	if m, ok := obj.(*loxMap); ok {
+		name := mapKey(key, e.bracket)
+		value, result := i.assignmentValue(e.operator, e.postfix, func() any {
+			current, ok := m.get(name)
+			if !ok {
+				logRuntimeError(e.bracket, fmt.Sprintf("Undefined key '%s'.", name))
+			}
+			return current
+		}, e.value)
+		m.set(name, value)
+		return result, nil
 	}
 
-	switch obj2 := obj.(type) {
-	case *loxList:
-		return obj2.setAtIndex(index, value), nil
-	default:
-		logRuntimeError(e.bracket, "Only lists can be mutated by index.")
+	list, ok := obj.(*loxList)
+	if !ok {
+		logRuntimeError(e.bracket, "Only lists and maps can be mutated by index.")
 		return nil, errors.New("unreachable")
 	}
+
+	if r, ok := key.(sliceRange); ok {
+		value, result := i.assignmentValue(e.operator, e.postfix, func() any {
+			return list.getSlice(r)
+		}, e.value)
+		values, ok := value.(*loxList)
+		if !ok {
+			logRuntimeError(e.bracket, "Can only assign a list to a slice.")
+		}
+		if err := list.setSlice(r, values.elements); err != nil {
+			logRuntimeError(e.bracket, err.Error())
+		}
+		return result, nil
+	}
+
+	index := indexValue(key, e.bracket)
+	value, result := i.assignmentValue(e.operator, e.postfix, func() any {
+		return list.getAtIndex(index)
+	}, e.value)
+	list.setAtIndex(index, value)
+	return result, nil
 }
 
 func (i interpreter) visitReturnStmt(s sReturn) error {
@@ -389,15 +609,21 @@ func (i interpreter) visitGetExpr(e eGet) (any, error) {
 		return nil, err
 	}
 
+	// built in data types only have methods and no fields
+	var method callable
 	switch obj2 := obj.(type) {
 	case loxClassInstance:
 		return obj2.get(e.name), nil
+	case string:
+		method = loxString(obj2).getMethod(e.name)
 	case dataType:
-		return obj2.getMethod(e.name), nil
-	default:
+		method = obj2.getMethod(e.name)
+	}
+	if method == nil {
 		logRuntimeError(e.name, "Only instances have properties.")
 		return nil, errors.New("unreachable")
 	}
+	return method, nil
 }
 
 func (i interpreter) visitSetExpr(e eSet) (any, error) {
@@ -407,8 +633,11 @@ func (i interpreter) visitSetExpr(e eSet) (any, error) {
 	}
 	switch obj2 := obj.(type) {
 	case loxClassInstance:
-		value := getJustVal(i.evaluate(e.value))
-		return obj2.set(e.name, value), nil
+		value, result := i.assignmentValue(e.operator, e.postfix, func() any {
+			return obj2.get(e.name)
+		}, e.value)
+		obj2.set(e.name, value)
+		return result, nil
 	default:
 		logRuntimeError(e.name, "Only instances have fields.")
 		return nil, errors.New("unreachable")
@@ -435,7 +664,7 @@ func (i interpreter) visitSuperExpr(e eSuper) (any, error) {
 	object2 := object.(loxClassInstance)
 	method, ok := superclass2.findMethod(e.method.lexeme)
 	if !ok {
-		logRuntimeError(e.method, "Undefined property '"+e.method.lexeme+"'.")
+		logUndefinedError(e.method, "Undefined property '"+e.method.lexeme+"'.", superclass2.methodNames())
 		return nil, errors.New("unreachable")
 	}
 	return method.bind(object2), nil
@@ -453,6 +682,8 @@ func (i interpreter) visitUnaryExpr(e eUnary) (any, error) {
 		return -right.(float64), nil
 	case tBang:
 		return !isTruthy(right), nil
+	case tBitNot:
+		return float64(^integerOperand(right, e.operator)), nil
 	default:
 		return nil, nil // unreachable
 	}
@@ -461,7 +692,7 @@ func (i interpreter) visitUnaryExpr(e eUnary) (any, error) {
 func (i interpreter) visitVariableExpr(e eVariable) (any, error) {
 	val, err := i.lookUpVariable(e.name)
 	if err != nil {
-		logRuntimeError(e.name, "Undefined variable '"+e.name.lexeme+"'.")
+		logUndefinedError(e.name, "Undefined variable '"+e.name.lexeme+"'.", i.env.names())
 	}
 	return val, err
 }
@@ -515,6 +746,40 @@ func validateNumberOperand2(num1, num2 any, operator token) {
 	}
 }
 
+func isInteger(value any) bool {
+	num, ok := value.(float64)
+	return ok && num == math.Trunc(num) && !math.IsInf(num, 0)
+}
+
+/*
+for bitwise operators, which work on 64 bit integers. Fractional numbers, and ones
+out of the range which would wrap around, are an error.
+*/
+func integerOperand(num any, operator token) int64 {
+	if !isInteger(num) {
+		logRuntimeError(operator, "Operand must be an integer.")
+	}
+	if !fitsInt64(num.(float64)) {
+		logRuntimeError(operator, "Operand must fit in a 64 bit integer.")
+	}
+	return int64(num.(float64))
+}
+
+func integerOperands(num1, num2 any, operator token) (int64, int64) {
+	if !isInteger(num1) || !isInteger(num2) {
+		logRuntimeError(operator, "Operands must be integers.")
+	}
+	if !fitsInt64(num1.(float64)) || !fitsInt64(num2.(float64)) {
+		logRuntimeError(operator, "Operands must fit in a 64 bit integer.")
+	}
+	return int64(num1.(float64)), int64(num2.(float64))
+}
+
+// 2^63 itself is a float64 but not an int64
+func fitsInt64(num float64) bool {
+	return num >= math.MinInt64 && num < math.MaxInt64
+}
+
 func validateNonZeroDenom(denom float64, operator token) {
 	if denom == 0 {
 		logRuntimeError(operator, "Division by zero")
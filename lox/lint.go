@@ -0,0 +1,557 @@
+package lox
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+/*
+Lint checks a program for code which is valid but likely a mistake, like a local
+variable which is never used. It only runs when the program has no errors, which
+are logged and their exit code returned instead.
+
+The rules in disabled aren't checked. A "// lox-ignore" comment turns off all the
+rules for the line it's at the end of, or for the next line when it's on its own
+line. "// lox-ignore shadowing, unused-local" turns off only the rules listed.
+*/
+func Lint(code []byte, disabled map[string]bool) ([]LintWarning, int) {
+	tokens := tokenize(code)
+	if hasParseError {
+		return nil, compileErrorExitCode
+	}
+	statements := newParser[expr](tokens).parse()
+	if hasParseError {
+		return nil, compileErrorExitCode
+	}
+	interpreter := newInterpreter()
+	newResolver(interpreter).resolve(statements)
+	if hasParseError {
+		return nil, compileErrorExitCode
+	}
+	return lint(tokens, statements, interpreter, disabled), 0
+}
+
+/*
+the unused-local rule is also checked when running a program, to log its warnings.
+It's the same check, so the warnings have the code and message of the rule.
+*/
+func logUnusedLocals(tokens []token, statements []stmt, interpreter *interpreter) {
+	disabled := make(map[string]bool)
+	for _, rule := range LintRules {
+		disabled[rule.Name] = rule.Name != WarnUnusedLocal
+	}
+	for _, warning := range lint(tokens, statements, interpreter, disabled) {
+		logWarning(warning.token, SeverityWarning, warning.Rule, warning.Message)
+	}
+}
+
+// checks a program which was resolved without errors, the natives are the interpreter's globals
+func lint(tokens []token, statements []stmt, interpreter *interpreter, disabled map[string]bool) []LintWarning {
+	l := &linter{
+		disabled: disabled,
+		ignored:  ignoredRules(tokens),
+		globals:  make(map[string]*lintVariable),
+		natives:  make(map[string]*lintVariable),
+	}
+	for name, native := range interpreter.globals.vars {
+		v := &lintVariable{name: token{lexeme: name}, kind: SymbolModule, arity: variadicArity}
+		if native, ok := native.(nativeFunction); ok {
+			v.kind, v.arity = SymbolFunction, native.arity()
+		}
+		l.natives[name] = v
+	}
+	// globals can be used before they're declared, like in functions called later
+	for _, st := range statements {
+		switch st := st.(type) {
+		case sVar:
+			l.declareGlobal(st.name, SymbolVariable)
+		case sFunction:
+			l.declareGlobal(st.name, SymbolFunction)
+		case sClass:
+			l.declareGlobal(st.name, SymbolClass)
+		}
+	}
+
+	l.stmts(statements)
+	// the variables called can be assigned after the call, so the calls are checked at the end
+	for _, call := range l.calls {
+		if arity, ok := call.callee.callArity(); ok && arity != len(call.expr.arguments) {
+			plural := "s"
+			if arity == 1 {
+				plural = ""
+			}
+			l.warn(call.expr.paren, "argument-count", "'%s' takes %d argument%s but is called with %d.",
+				call.callee.name.lexeme, arity, plural, len(call.expr.arguments))
+		}
+	}
+	slices.SortStableFunc(l.warnings, func(a, b LintWarning) int {
+		return compareLocations(a.Location, b.Location)
+	})
+	return l.warnings
+}
+
+type LintWarning struct {
+	Location Location
+	Rule     string
+	Message  string
+
+	token token // the warning is at, for logging it
+}
+
+// names of the rules and what they check, in the order they're documented
+var LintRules = []struct{ Name, Description string }{
+	{WarnUnusedLocal, "local variables, functions and classes which are never used"},
+	{"unused-parameter", "parameters which are never used"},
+	{"shadowing", "declarations hiding a variable of an enclosing scope or a global"},
+	{"unreachable-code", "statements after a return"},
+	{"no-effect-assignment", "assigning a variable or a property to itself"},
+	{"not-callable", "calling a literal, which isn't a function or a class"},
+	{"argument-count", "calling a function or class with the wrong number of arguments"},
+	{"mixed-comparison", "comparing literals of different types"},
+}
+
+/*
+a variable as far as the linter knows it. Names starting with "_" are never reported
+as unused, which is how a parameter can be marked as not needed.
+*/
+type lintVariable struct {
+	name         token
+	kind         SymbolKind
+	arity        int           // for functions and classes with an init, variadicArity if it isn't known
+	superclass   *lintVariable // for classes without an init, which take the arguments of the superclass's
+	isUsed       bool
+	isAssigned   bool // after its declaration, so it may not be what it was declared as
+	isRedeclared bool // globals can be declared again
+}
+
+type lintCall struct {
+	callee *lintVariable
+	expr   eCall
+}
+
+type linter struct {
+	warnings []LintWarning
+	disabled map[string]bool
+	ignored  map[int][]string // rules turned off for each line, empty for all of them
+	scopes   []map[string]*lintVariable
+	globals  map[string]*lintVariable // declared in the program
+	natives  map[string]*lintVariable
+	calls    []lintCall
+}
+
+var _ exprVisitor = (*linter)(nil)
+var _ stmtVisitor = (*linter)(nil)
+
+// the lines with lox-ignore comments and the rules listed in them
+func ignoredRules(tokens []token) map[int][]string {
+	ignored := make(map[int][]string)
+	for _, t := range tokens {
+		if t.trivia == nil {
+			continue
+		}
+		for _, c := range t.trivia.comments {
+			text := strings.TrimSpace(strings.TrimPrefix(c.text, "//"))
+			rules, found := strings.CutPrefix(text, "lox-ignore")
+			if !found || (rules != "" && rules[0] != ' ' && rules[0] != '\t') {
+				continue
+			}
+			line := c.line
+			if c.ownLine {
+				line++
+			}
+			ignored[line] = strings.FieldsFunc(rules, func(r rune) bool {
+				return r == ',' || r == ' ' || r == '\t'
+			})
+		}
+	}
+	return ignored
+}
+
+func (l *linter) warn(at token, rule string, format string, args ...any) {
+	if l.disabled[rule] {
+		return
+	}
+	if rules, ok := l.ignored[at.line]; ok && (len(rules) == 0 || slices.Contains(rules, rule)) {
+		return
+	}
+	l.warnings = append(l.warnings, LintWarning{
+		Location: tokenLocation(at),
+		Rule:     rule,
+		Message:  fmt.Sprintf(format, args...),
+		token:    at,
+	})
+}
+
+func (l *linter) declareGlobal(name token, kind SymbolKind) {
+	if v, exists := l.globals[name.lexeme]; exists {
+		v.isRedeclared = true
+		return
+	}
+	l.globals[name.lexeme] = &lintVariable{name: name, kind: kind, arity: variadicArity}
+}
+
+func (l *linter) beginScope() {
+	l.scopes = append(l.scopes, make(map[string]*lintVariable))
+}
+
+func (l *linter) endScope() {
+	scope := l.scopes[len(l.scopes)-1]
+	l.scopes = l.scopes[:len(l.scopes)-1]
+	var unused []*lintVariable
+	for _, v := range scope {
+		if !v.isUsed && !strings.HasPrefix(v.name.lexeme, "_") {
+			unused = append(unused, v)
+		}
+	}
+	// the warnings are sorted later, but the order of the ones on a line shouldn't depend on the map
+	slices.SortFunc(unused, func(a, b *lintVariable) int {
+		return compareLocations(tokenLocation(a.name), tokenLocation(b.name))
+	})
+	for _, v := range unused {
+		switch v.kind {
+		case SymbolParameter:
+			l.warn(v.name, "unused-parameter", "Parameter '%s' is never used.", v.name.lexeme)
+		case SymbolFunction:
+			l.warn(v.name, WarnUnusedLocal, "Local function '%s' is never used.", v.name.lexeme)
+		case SymbolClass:
+			l.warn(v.name, WarnUnusedLocal, "Local class '%s' is never used.", v.name.lexeme)
+		default:
+			l.warn(v.name, WarnUnusedLocal, "Local variable '%s' is never used.", v.name.lexeme)
+		}
+	}
+}
+
+// the variable for the name, the global one when it's at the top level
+func (l *linter) declare(name token, kind SymbolKind) *lintVariable {
+	if len(l.scopes) == 0 {
+		return l.globals[name.lexeme]
+	}
+	var shadowed *lintVariable
+	for idx := len(l.scopes) - 2; idx >= 0 && shadowed == nil; idx-- {
+		shadowed = l.scopes[idx][name.lexeme]
+	}
+	if shadowed == nil {
+		shadowed = l.globals[name.lexeme]
+	}
+	if shadowed != nil {
+		l.warn(name, "shadowing", "'%s' shadows the variable declared on line %d.", name.lexeme, shadowed.name.line)
+	}
+	v := &lintVariable{name: name, kind: kind, arity: variadicArity}
+	l.scopes[len(l.scopes)-1][name.lexeme] = v
+	return v
+}
+
+// the variable the name refers to, nil if it isn't declared
+func (l *linter) lookup(name token) *lintVariable {
+	for idx := len(l.scopes) - 1; idx >= 0; idx-- {
+		if v, exists := l.scopes[idx][name.lexeme]; exists {
+			return v
+		}
+	}
+	if v, exists := l.globals[name.lexeme]; exists {
+		return v
+	}
+	return l.natives[name.lexeme]
+}
+
+// how many arguments it has to be called with, false if it can be any number or isn't known
+func (v *lintVariable) callArity() (int, bool) {
+	seen := make(map[*lintVariable]bool)
+	for v.superclass != nil && !v.isAssigned && !v.isRedeclared && !seen[v] {
+		seen[v] = true
+		v = v.superclass
+	}
+	if v.isAssigned || v.isRedeclared || seen[v] {
+		return 0, false
+	}
+	return v.arity, v.arity != variadicArity
+}
+
+func (l *linter) stmts(statements []stmt) {
+	reported := false
+	for idx, st := range statements {
+		st.accept(l)
+		if reported || idx == len(statements)-1 || !alwaysReturns(st) {
+			continue
+		}
+		start, ok := stmtStart(statements[idx+1])
+		switch next := statements[idx+1].(type) {
+		case sBlock:
+			start, ok = next.start, true
+		case sFor:
+			start, ok = next.keyword, true
+		}
+		if ok {
+			l.warn(start, "unreachable-code", "Unreachable code after return.")
+		}
+		reported = true
+	}
+}
+
+func alwaysReturns(st stmt) bool {
+	switch st := st.(type) {
+	case sReturn:
+		return true
+	case sBlock:
+		return slices.ContainsFunc(st.statements, alwaysReturns)
+	case sIf:
+		return st.elseBranch != nil && alwaysReturns(st.thenBranch) && alwaysReturns(st.elseBranch)
+	default:
+		return false
+	}
+}
+
+func (l *linter) exprs(exprs ...expr) {
+	for _, e := range exprs {
+		if e != nil {
+			e.accept(l)
+		}
+	}
+}
+
+func (l *linter) visitExprStmt(st sExpr) error {
+	l.exprs(st.expression)
+	return nil
+}
+
+func (l *linter) visitPrintStmt(st sPrint) error {
+	l.exprs(st.expression)
+	return nil
+}
+
+func (l *linter) visitVarStmt(st sVar) error {
+	// the initializer can't use the variable, it's a resolve error
+	l.exprs(st.initializer)
+	l.declare(st.name, SymbolVariable)
+	return nil
+}
+
+func (l *linter) visitBlockStmt(st sBlock) error {
+	l.beginScope()
+	l.stmts(st.statements)
+	l.endScope()
+	return nil
+}
+
+func (l *linter) visitIfStmt(st sIf) error {
+	l.exprs(st.condition)
+	st.thenBranch.accept(l)
+	if st.elseBranch != nil {
+		st.elseBranch.accept(l)
+	}
+	return nil
+}
+
+func (l *linter) visitWhileStmt(st sWhile) error {
+	l.exprs(st.condition)
+	return st.body.accept(l)
+}
+
+func (l *linter) visitForStmt(st sFor) error {
+	return st.desugared.accept(l)
+}
+
+func (l *linter) visitFunctionStmt(st sFunction) error {
+	v := l.declare(st.name, SymbolFunction)
+	v.arity = len(st.parameters)
+	l.function(st)
+	return nil
+}
+
+func (l *linter) function(function sFunction) {
+	l.beginScope()
+	for _, param := range function.parameters {
+		l.declare(param, SymbolParameter)
+	}
+	l.stmts(function.body)
+	l.endScope()
+}
+
+func (l *linter) visitReturnStmt(st sReturn) error {
+	l.exprs(st.value)
+	return nil
+}
+
+func (l *linter) visitClassStmt(st sClass) error {
+	v := l.declare(st.name, SymbolClass)
+	v.arity = 0
+	if st.superclass != nil {
+		l.exprs(*st.superclass)
+		v.arity = variadicArity
+		if superclass := l.lookup(st.superclass.name); superclass != nil && superclass.kind == SymbolClass {
+			v.superclass = superclass
+		}
+	}
+	for _, method := range st.methods {
+		if method.name.lexeme == "init" {
+			v.arity, v.superclass = len(method.parameters), nil
+		}
+		l.function(method)
+	}
+	return nil
+}
+
+func (l *linter) visitAssignExpr(e eAssign) (any, error) {
+	l.exprs(e.value)
+	if v := l.lookup(e.name); v != nil {
+		v.isAssigned = true
+	}
+	if value, ok := unwrapGrouping(e.value).(eVariable); ok && e.operator.tokenType == tEqual && value.name.lexeme == e.name.lexeme {
+		l.warn(e.name, "no-effect-assignment", "Assigning '%s' to itself has no effect.", e.name.lexeme)
+	}
+	return nil, nil
+}
+
+func (l *linter) visitSetExpr(e eSet) (any, error) {
+	l.exprs(e.object, e.value)
+	if value, ok := unwrapGrouping(e.value).(eGet); ok && e.operator.tokenType == tEqual &&
+		value.name.lexeme == e.name.lexeme && isSameObject(e.object, value.object) {
+		l.warn(e.name, "no-effect-assignment", "Assigning '%s' to itself has no effect.", e.name.lexeme)
+	}
+	return nil, nil
+}
+
+// both are this or the same variable, so reading them can't have side effects
+func isSameObject(a expr, b expr) bool {
+	switch a := unwrapGrouping(a).(type) {
+	case eThis:
+		_, ok := unwrapGrouping(b).(eThis)
+		return ok
+	case eVariable:
+		b, ok := unwrapGrouping(b).(eVariable)
+		return ok && a.name.lexeme == b.name.lexeme
+	default:
+		return false
+	}
+}
+
+func unwrapGrouping(e expr) expr {
+	for {
+		grouping, ok := e.(eGrouping)
+		if !ok {
+			return e
+		}
+		e = grouping.expression
+	}
+}
+
+func (l *linter) visitBinaryExpr(e eBinary) (any, error) {
+	l.exprs(e.left, e.right)
+	switch e.operator.tokenType {
+	case tEqualEqual, tBangEqual, tLess, tLessEqual, tGreater, tGreaterEqual:
+	default:
+		return nil, nil
+	}
+	left, right := literalType(e.left), literalType(e.right)
+	if left == "" || right == "" || left == right {
+		return nil, nil
+	}
+	result := "fails at runtime"
+	if e.operator.tokenType == tEqualEqual {
+		result = "is always false"
+	} else if e.operator.tokenType == tBangEqual {
+		result = "is always true"
+	}
+	l.warn(e.operator, "mixed-comparison", "Comparing a %s literal with a %s literal %s.", left, right, result)
+	return nil, nil
+}
+
+// the type of the value of a literal, "" for other expressions
+func literalType(e expr) string {
+	switch e := unwrapGrouping(e).(type) {
+	case eLiteral:
+		switch e.value.(type) {
+		case float64:
+			return "number"
+		case string:
+			return "string"
+		case bool:
+			return "boolean"
+		case nil:
+			return "nil"
+		}
+	case eTemplate:
+		return "string"
+	case eList:
+		return "list"
+	}
+	return ""
+}
+
+func (l *linter) visitCallExpr(e eCall) (any, error) {
+	l.exprs(e.callee)
+	l.exprs(e.arguments...)
+	if kind := literalType(e.callee); kind != "" {
+		l.warn(e.paren, "not-callable", "Calling a %s literal, which isn't a function or a class.", kind)
+	} else if callee, ok := e.callee.(eVariable); ok {
+		if v := l.lookup(callee.name); v != nil {
+			l.calls = append(l.calls, lintCall{callee: v, expr: e})
+		}
+	}
+	return nil, nil
+}
+
+func (l *linter) visitVariableExpr(e eVariable) (any, error) {
+	if v := l.lookup(e.name); v != nil {
+		v.isUsed = true
+	}
+	return nil, nil
+}
+
+func (l *linter) visitGetExpr(e eGet) (any, error) {
+	l.exprs(e.object)
+	return nil, nil
+}
+
+func (l *linter) visitGroupingExpr(e eGrouping) (any, error) {
+	l.exprs(e.expression)
+	return nil, nil
+}
+
+func (l *linter) visitLiteralExpr(e eLiteral) (any, error) {
+	return nil, nil
+}
+
+func (l *linter) visitLogicalExpr(e eLogical) (any, error) {
+	l.exprs(e.left, e.right)
+	return nil, nil
+}
+
+func (l *linter) visitSuperExpr(e eSuper) (any, error) {
+	return nil, nil
+}
+
+func (l *linter) visitThisExpr(e eThis) (any, error) {
+	return nil, nil
+}
+
+func (l *linter) visitUnaryExpr(e eUnary) (any, error) {
+	l.exprs(e.right)
+	return nil, nil
+}
+
+func (l *linter) visitListExpr(e eList) (any, error) {
+	l.exprs(e.elements...)
+	return nil, nil
+}
+
+func (l *linter) visitGetIndexExpr(e eGetIndex) (any, error) {
+	l.exprs(e.object, e.key)
+	return nil, nil
+}
+
+func (l *linter) visitSetIndexExpr(e eSetIndex) (any, error) {
+	l.exprs(e.object, e.key, e.value)
+	return nil, nil
+}
+
+func (l *linter) visitTemplateExpr(e eTemplate) (any, error) {
+	l.exprs(e.parts...)
+	return nil, nil
+}
+
+func (l *linter) visitSliceExpr(e eSlice) (any, error) {
+	l.exprs(e.start, e.end, e.step)
+	return nil, nil
+}
@@ -1,5 +1,7 @@
 package lox
 
+import "fmt"
+
 const compileErrorExitCode = 65
 const runtimeErrorExitCode = 70
 
@@ -7,17 +9,51 @@ var hasParseError bool
 var hasRuntimeError bool
 
 type TokenLogMeta struct {
-	Line int
-	Col  int
+	Line      int // where the token ends, errors are reported on it
+	Col       int // just after the token
+	StartLine int // where the token starts, before Line only for multiline strings
+	StartCol  int
+
+	Stack      []StackFrame // for runtime errors, the functions being called innermost first, ending with "<script>"
+	Suggestion string       // for undefined names, the defined name which may have been meant, "" if none is close
+}
+
+// how serious a warning is, warnings don't stop the program from running
+type Severity int
+
+const (
+	SeverityInfo    Severity = iota // probably intended, but could be written more clearly
+	SeverityWarning                 // likely a mistake
+)
+
+func (s Severity) String() string {
+	if s == SeverityInfo {
+		return "Info"
+	}
+	return "Warning"
 }
 
+// stable codes of the warnings, for tools to recognise or filter them
+const (
+	WarnUnusedLocal    = "unused-local" // the lint rule, see lint.go
+	WarnStringCoercion = "string-coercion"
+)
+
 // interface as its different for normal run and wasm
 type Logger struct {
-	Input        func(prompt string) (string, error)  // corresponds to input in lox
+	Input        func(prompt string) (string, error)  // corresponds to input in lox, gives the line without the newline
+	ReadLine     func() (string, error)               // corresponds to readLine in lox, io.EOF at the end of input
+	ReadAll      func() (string, error)               // corresponds to readAll in lox, everything left in the input
 	Print        func(s string)                       // corresponds to print in lox
 	ScanError    func(line int, col int, msg string)  // error during tokenization
-	ParseError   func(token TokenLogMeta, msg string) // error during parsing and resolving(static analysis)
+	ParseError   func(token TokenLogMeta, msg string) // error during parsing, and resolving if ResolveError is nil
 	RuntimeError func(token TokenLogMeta, msg string) // error during interpretation
+	// error found by the resolver(static analysis) like using 'this' outside a class, can be nil
+	ResolveError func(token TokenLogMeta, msg string)
+	// non fatal diagnostic found during static analysis, can be nil to ignore them
+	Warning func(token TokenLogMeta, severity Severity, code string, msg string)
+	// a bug in the interpreter itself rather than in the program, can be nil to print it
+	InternalError func(msg string)
 }
 
 var logger Logger
@@ -26,6 +62,22 @@ func SetLogger(logger2 Logger) {
 	logger = logger2
 }
 
+/*
+runs fn with errors going to the given logger, restoring the host's logger and
+the error state after it
+*/
+func withLogger(l Logger, fn func()) {
+	origLogger, origParseError, origRuntimeError := logger, hasParseError, hasRuntimeError
+	origCallStack := callStack
+	defer func() {
+		logger, hasParseError, hasRuntimeError = origLogger, origParseError, origRuntimeError
+		callStack = origCallStack
+	}()
+	logger = l
+	hasParseError, hasRuntimeError = false, false
+	fn()
+}
+
 func ResetErrorState() {
 	hasParseError = false
 	hasRuntimeError = false
@@ -36,16 +88,56 @@ func logScanError(line int, col int, msg string) {
 	logger.ScanError(line, col, msg)
 }
 
+func tokenLogMeta(token token) TokenLogMeta {
+	return TokenLogMeta{Line: token.line, Col: token.column, StartLine: token.startLine, StartCol: token.startColumn()}
+}
+
 func logParseError(token token, msg string) {
 	hasParseError = true
-	logger.ParseError(TokenLogMeta{Line: token.line, Col: token.column}, msg)
+	logger.ParseError(tokenLogMeta(token), msg)
+}
+
+func logResolveError(token token, msg string) {
+	if logger.ResolveError == nil {
+		logParseError(token, msg)
+		return
+	}
+	hasParseError = true
+	logger.ResolveError(tokenLogMeta(token), msg)
+}
+
+func logWarning(token token, severity Severity, code string, msg string) {
+	if logger.Warning == nil {
+		return
+	}
+	logger.Warning(tokenLogMeta(token), severity, code, msg)
+}
+
+// for a panic which isn't a runtime error of the program
+func logInternalError(r any) {
+	if logger.InternalError == nil {
+		fmt.Println("Recovered from run time error panic, Error: ", r)
+		return
+	}
+	logger.InternalError(fmt.Sprint(r))
 }
 
 /*
 this function also panics, as for runtime error we can't proceed further in interpreter
 */
 func logRuntimeError(token token, msg string) {
+	runtimeError(token, msg, "")
+}
+
+// a runtime error for an undefined name, suggesting the closest of the defined names
+func logUndefinedError(name token, msg string, defined []string) {
+	runtimeError(name, msg, closestName(name.lexeme, defined))
+}
+
+func runtimeError(token token, msg string, suggestion string) {
 	hasRuntimeError = true
-	logger.RuntimeError(TokenLogMeta{Line: token.line, Col: token.column}, msg)
+	meta := tokenLogMeta(token)
+	meta.Stack, meta.Suggestion = stackTrace(token), suggestion
+	logger.RuntimeError(meta, msg)
 	panic("runtime error")
 }
@@ -29,16 +29,37 @@ func tokenize(code []byte) []token {
 
 }
 
-func Parse(code []byte) {
+/*
+gives the AST of the program as S-expressions, a statement per line. A lone expression
+without a ";", like in the tests of the parsing chapter, is printed by itself. With keepFor
+the for loops are printed as they're written, rather than as the while loops they run as.
+*/
+func Parse(code []byte, keepFor bool) (string, int) {
 	tokens := tokenize(code)
-	parser := newParser[expr](tokens)
-	parsedExpr := parser.parseExpression()
 	if hasParseError {
-		os.Exit(65)
-	} else {
-		printer := astPrinter{}
-		printer.print(parsedExpr)
+		return "", compileErrorExitCode
+	}
+	printer := newAstPrinter(keepFor)
+	if isLoneExpression(tokens) {
+		parsedExpr := newParser[expr](tokens).parseExpression()
+		return printer.exprString(parsedExpr) + "\n", 0
+	}
+	statements := newParser[expr](tokens).parse()
+	if hasParseError {
+		return "", compileErrorExitCode
 	}
+	return printer.printProgram(statements), 0
+}
+
+// if all the tokens are a single expression, trying it out without logging any errors
+func isLoneExpression(tokens []token) bool {
+	isLone := false
+	withLogger(Logger{ParseError: func(TokenLogMeta, string) {}}, func() {
+		parser := newParser[expr](tokens)
+		_, err := parser.expression()
+		isLone = err == nil && !hasParseError && parser.isAtEnd()
+	})
+	return isLone
 }
 
 func Visualize(code []byte) {
@@ -59,8 +80,11 @@ func Visualize(code []byte) {
 func Evaluate(code []byte) {
 	defer func() {
 		if r := recover(); r != nil {
+			if exit, ok := r.(exitRequest); ok {
+				os.Exit(exit.code)
+			}
 			if !hasRuntimeError {
-				fmt.Println("Recovered from run time error panic, Error: ", r)
+				logInternalError(r)
 			}
 			os.Exit(70)
 		}
@@ -86,12 +110,21 @@ func Evaluate(code []byte) {
 }
 
 func Run(code []byte, ctx context.Context) (exitCode int) {
+	return run(code, ctx, nil)
+}
+
+func run(code []byte, ctx context.Context, debugger *Debugger) (exitCode int) {
 	exitCode = 0
+	callStack = nil
 
 	defer func() {
 		if r := recover(); r != nil {
+			if exit, ok := r.(exitRequest); ok {
+				exitCode = exit.code
+				return
+			}
 			if !hasRuntimeError {
-				fmt.Println("Recovered from run time error panic, Error: ", r)
+				logInternalError(r)
 			}
 			exitCode = runtimeErrorExitCode
 		}
@@ -106,6 +139,7 @@ func Run(code []byte, ctx context.Context) (exitCode int) {
 		return
 	} else {
 		interpreter := newInterpreter()
+		interpreter.debugger = debugger
 
 		resolver := newResolver(interpreter)
 		resolver.resolve(statements)
@@ -113,6 +147,9 @@ func Run(code []byte, ctx context.Context) (exitCode int) {
 			exitCode = compileErrorExitCode
 			return
 		}
+		if logger.Warning != nil {
+			logUnusedLocals(tokens, statements, interpreter)
+		}
 
 		interpreter.interpret(statements, ctx)
 		if hasRuntimeError {
@@ -135,6 +172,8 @@ func getLiteralStr(literal interface{}) string {
 		return literal
 	case *loxList:
 		return literal.String()
+	case *loxMap:
+		return literal.String()
 	default:
 		return fmt.Sprintf("%v", literal)
 	}
@@ -0,0 +1,143 @@
+package lox
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+)
+
+/*
+File natives, these go through the FileSystem set by the host. Failures like
+missing files are lox runtime errors.
+*/
+func defineFileFunctions(globals *environment) {
+	globals.define("readFile", nativeFunction{
+		arityCnt: 1,
+		fn: func(i interpreter, a []any) (any, error) {
+			data, err := readFileArg("readFile", a)
+			return string(data), err
+		},
+	})
+	// lines of the file without the line endings
+	globals.define("readLines", nativeFunction{
+		arityCnt: 1,
+		fn: func(i interpreter, a []any) (any, error) {
+			data, err := readFileArg("readLines", a)
+			if err != nil {
+				return nil, err
+			}
+			content := strings.TrimSuffix(string(data), "\n")
+			lines := []any{}
+			if content != "" {
+				for _, line := range strings.Split(content, "\n") {
+					lines = append(lines, strings.TrimSuffix(line, "\r"))
+				}
+			}
+			return getLoxList(lines), nil
+		},
+	})
+	globals.define("writeFile", nativeFunction{ // replaces the content, creating the file if needed
+		arityCnt: 2,
+		fn: func(i interpreter, a []any) (any, error) {
+			return nil, writeFileArgs("writeFile", a, func(fs FileSystem, name string, data []byte) error {
+				return fs.WriteFile(name, data)
+			})
+		},
+	})
+	globals.define("appendFile", nativeFunction{
+		arityCnt: 2,
+		fn: func(i interpreter, a []any) (any, error) {
+			return nil, writeFileArgs("appendFile", a, func(fs FileSystem, name string, data []byte) error {
+				return fs.AppendFile(name, data)
+			})
+		},
+	})
+	globals.define("fileExists", nativeFunction{
+		arityCnt: 1,
+		fn: func(i interpreter, a []any) (any, error) {
+			name, err := fileNameArg("fileExists", a)
+			if err != nil {
+				return nil, err
+			}
+			return fileSystem.Exists(name), nil
+		},
+	})
+	globals.define("listDir", nativeFunction{
+		arityCnt: 1,
+		fn: func(i interpreter, a []any) (any, error) {
+			name, err := fileNameArg("listDir", a)
+			if err != nil {
+				return nil, err
+			}
+			entries, err := fileSystem.ListDir(name)
+			if err != nil {
+				return nil, fileError("listDir", name, err)
+			}
+			elements := make([]any, len(entries))
+			for idx, entry := range entries {
+				elements[idx] = entry
+			}
+			return getLoxList(elements), nil
+		},
+	})
+	globals.define("deleteFile", nativeFunction{
+		arityCnt: 1,
+		fn: func(i interpreter, a []any) (any, error) {
+			name, err := fileNameArg("deleteFile", a)
+			if err != nil {
+				return nil, err
+			}
+			if err := fileSystem.Remove(name); err != nil {
+				return nil, fileError("deleteFile", name, err)
+			}
+			return nil, nil
+		},
+	})
+}
+
+// the file name is always the first argument
+func fileNameArg(fnName string, args []any) (string, error) {
+	if fileSystem == nil {
+		return "", nativeErrorf("%s() can't be used, there is no file system available.", fnName)
+	}
+	return stringArg(fnName, args, 0)
+}
+
+func readFileArg(fnName string, args []any) ([]byte, error) {
+	name, err := fileNameArg(fnName, args)
+	if err != nil {
+		return nil, err
+	}
+	data, err := fileSystem.ReadFile(name)
+	if err != nil {
+		return nil, fileError(fnName, name, err)
+	}
+	return data, nil
+}
+
+// args are the file name and the content to write
+func writeFileArgs(fnName string, args []any, write func(fs FileSystem, name string, data []byte) error) error {
+	name, err := fileNameArg(fnName, args)
+	if err != nil {
+		return err
+	}
+	content, err := stringArg(fnName, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := write(fileSystem, name, []byte(content)); err != nil {
+		return fileError(fnName, name, err)
+	}
+	return nil
+}
+
+func fileError(fnName string, name string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return nativeErrorf("%s() failed, '%s' does not exist.", fnName, name)
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	return nativeErrorf("%s() failed for '%s': %s.", fnName, name, err.Error())
+}
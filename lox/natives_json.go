@@ -0,0 +1,222 @@
+package lox
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+/*
+jsonParse and jsonStringify. JSON arrays map to lists and objects to maps, with
+the keys in the order they appear in the text.
+*/
+func defineJSONFunctions(globals *environment) {
+	globals.define("jsonParse", nativeFunction{
+		arityCnt: 1,
+		fn: func(i interpreter, a []any) (any, error) {
+			str, err := stringArg("jsonParse", a, 0)
+			if err != nil {
+				return nil, err
+			}
+			return parseJSON(str)
+		},
+	})
+	globals.define("jsonStringify", nativeFunction{ // jsonStringify(value, indent), indent is optional
+		arityCnt: variadicArity,
+		fn: func(i interpreter, a []any) (any, error) {
+			if err := argCountBetween("jsonStringify", a, 1, 2); err != nil {
+				return nil, err
+			}
+			indent := 0
+			if len(a) == 2 && a[1] != nil {
+				var err error
+				indent, err = intArg("jsonStringify", a, 1)
+				if err != nil {
+					return nil, err
+				}
+				if indent < 0 {
+					return nil, nativeErrorf("jsonStringify() indent can't be negative.")
+				}
+			}
+			w := jsonWriter{indent: strings.Repeat(" ", indent), visiting: make(map[any]bool)}
+			if err := w.write(a[0], 0); err != nil {
+				return nil, err
+			}
+			return w.sb.String(), nil
+		},
+	})
+}
+
+func parseJSON(str string) (any, error) {
+	dec := json.NewDecoder(strings.NewReader(str))
+	value, err := parseJSONValue(dec)
+	if err != nil {
+		// the decoder reports how many bytes it read, including the offending one.
+		// If the text ended early, the error is at the end of it.
+		var syntaxErr *json.SyntaxError
+		offset := int64(len(str))
+		if errors.As(err, &syntaxErr) && !strings.HasSuffix(err.Error(), "end of JSON input") {
+			offset = max(syntaxErr.Offset-1, 0)
+		} else if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, nativeErrorf("jsonParse() invalid JSON at byte %d: %s.", offset, err.Error())
+	}
+
+	rest := str[dec.InputOffset():]
+	if trimmed := strings.TrimLeftFunc(rest, unicode.IsSpace); trimmed != "" {
+		offset := len(str) - len(trimmed)
+		return nil, nativeErrorf("jsonParse() invalid JSON at byte %d: unexpected data after the value.", offset)
+	}
+	return value, nil
+}
+
+// the decoder validates the structure, so keys are always strings and delimiters match
+func parseJSONValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil // string, float64, bool or nil
+	}
+
+	if delim == '[' {
+		elements := []any{}
+		for dec.More() {
+			value, err := parseJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return getLoxList(elements), nil
+	}
+
+	m := getLoxMap()
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		value, err := parseJSONValue(dec)
+		if err != nil {
+			return nil, err
+		}
+		m.set(key.(string), value)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+/*
+writes lox values as JSON, class instances are written as objects of their fields.
+visiting has the lists, maps and instances currently being written, to catch cycles.
+*/
+type jsonWriter struct {
+	sb       strings.Builder
+	indent   string
+	visiting map[any]bool
+}
+
+func (w *jsonWriter) write(value any, depth int) error {
+	switch value := value.(type) {
+	case nil:
+		w.sb.WriteString("null")
+	case bool, string:
+		w.writeEncoded(value)
+	case float64:
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return nativeErrorf("jsonStringify() can't convert %s to JSON.", getLiteralStr(value))
+		}
+		w.writeEncoded(value)
+	case *loxList:
+		return w.writeContainer(value, depth, '[', ']', value.elements, nil)
+	case *loxMap:
+		return w.writeContainer(value, depth, '{', '}', nil, value)
+	case loxClassInstance:
+		fields := getLoxMap()
+		names := make([]string, 0, len(value.fields))
+		for name := range value.fields {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			fields.set(name, value.fields[name])
+		}
+		// the fields map is shared by all copies of the instance, so it identifies it
+		return w.writeContainer(reflect.ValueOf(value.fields).Pointer(), depth, '{', '}', nil, fields)
+	default:
+		return nativeErrorf("jsonStringify() can't convert %s to JSON.", getLiteralStr(value))
+	}
+	return nil
+}
+
+// writes either the elements of a list or the entries of a map
+func (w *jsonWriter) writeContainer(identity any, depth int, open, close byte, elements []any, m *loxMap) error {
+	if w.visiting[identity] {
+		return nativeErrorf("jsonStringify() can't convert a value which contains itself.")
+	}
+	w.visiting[identity] = true
+	defer delete(w.visiting, identity)
+
+	count := len(elements)
+	if m != nil {
+		count = len(m.keys)
+	}
+	w.sb.WriteByte(open)
+	for idx := range count {
+		if idx > 0 {
+			w.sb.WriteByte(',')
+		}
+		w.writeNewline(depth + 1)
+		value := any(nil)
+		if m != nil {
+			key := m.keys[idx]
+			w.writeEncoded(key)
+			w.sb.WriteByte(':')
+			if w.indent != "" {
+				w.sb.WriteByte(' ')
+			}
+			value = m.values[key]
+		} else {
+			value = elements[idx]
+		}
+		if err := w.write(value, depth+1); err != nil {
+			return err
+		}
+	}
+	if count > 0 {
+		w.writeNewline(depth)
+	}
+	w.sb.WriteByte(close)
+	return nil
+}
+
+func (w *jsonWriter) writeNewline(depth int) {
+	if w.indent == "" {
+		return
+	}
+	w.sb.WriteByte('\n')
+	w.sb.WriteString(strings.Repeat(w.indent, depth))
+}
+
+// strings, numbers and bools are encoded by the standard library, without escaping <, > and &
+func (w *jsonWriter) writeEncoded(value any) {
+	var sb strings.Builder
+	enc := json.NewEncoder(&sb)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(value) // can't fail for these types
+	w.sb.WriteString(strings.TrimSuffix(sb.String(), "\n"))
+}
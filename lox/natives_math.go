@@ -0,0 +1,131 @@
+package lox
+
+import (
+	"math"
+)
+
+/*
+Math functions and constants available as globals in lox. The arguments are
+validated so wrong usage is a lox runtime error instead of a crash.
+*/
+func defineMathFunctions(globals *environment) {
+	globals.define("PI", math.Pi)
+	globals.define("E", math.E)
+
+	defineMathFn1(globals, "floor", math.Floor)
+	defineMathFn1(globals, "ceil", math.Ceil)
+	defineMathFn1(globals, "round", math.Round) // half away from zero
+	defineMathFn1(globals, "trunc", math.Trunc)
+	defineMathFn1(globals, "abs", math.Abs)
+	defineMathFn1(globals, "sqrt", math.Sqrt)
+	defineMathFn1(globals, "sin", math.Sin)
+	defineMathFn1(globals, "cos", math.Cos)
+	defineMathFn1(globals, "tan", math.Tan)
+	defineMathFn1(globals, "log", math.Log) // natural log
+	defineMathFn1(globals, "exp", math.Exp)
+
+	defineMathFn2(globals, "pow", math.Pow)
+	defineMathFn2(globals, "atan2", math.Atan2)
+
+	globals.define("isNaN", nativeFunction{
+		arityCnt: 1,
+		fn: func(i interpreter, a []any) (any, error) {
+			num, err := numberArg("isNaN", a, 0)
+			return math.IsNaN(num), err
+		},
+	})
+	globals.define("isInfinite", nativeFunction{
+		arityCnt: 1,
+		fn: func(i interpreter, a []any) (any, error) {
+			num, err := numberArg("isInfinite", a, 0)
+			return math.IsInf(num, 0), err
+		},
+	})
+	globals.define("min", nativeFunction{
+		arityCnt: variadicArity,
+		fn: func(i interpreter, a []any) (any, error) {
+			return extremum("min", a, func(x, y float64) bool { return x < y })
+		},
+	})
+	globals.define("max", nativeFunction{
+		arityCnt: variadicArity,
+		fn: func(i interpreter, a []any) (any, error) {
+			return extremum("max", a, func(x, y float64) bool { return x > y })
+		},
+	})
+	globals.define("clamp", nativeFunction{ // clamp(value, low, high)
+		arityCnt: 3,
+		fn: func(i interpreter, a []any) (any, error) {
+			nums := make([]float64, 3)
+			for position := range nums {
+				num, err := numberArg("clamp", a, position)
+				if err != nil {
+					return nil, err
+				}
+				nums[position] = num
+			}
+			if nums[1] > nums[2] {
+				return nil, nativeErrorf("clamp() low can't be greater than high.")
+			}
+			return min(max(nums[0], nums[1]), nums[2]), nil
+		},
+	})
+	globals.define("random", nativeFunction{ // float in [0, 1)
+		fn: func(i interpreter, a []any) (any, error) {
+			return rng.Float64(), nil
+		},
+	})
+}
+
+func defineMathFn1(globals *environment, name string, fn func(float64) float64) {
+	globals.define(name, nativeFunction{
+		arityCnt: 1,
+		fn: func(i interpreter, a []any) (any, error) {
+			num, err := numberArg(name, a, 0)
+			if err != nil {
+				return nil, err
+			}
+			return fn(num), nil
+		},
+	})
+}
+
+func defineMathFn2(globals *environment, name string, fn func(float64, float64) float64) {
+	globals.define(name, nativeFunction{
+		arityCnt: 2,
+		fn: func(i interpreter, a []any) (any, error) {
+			x, err := numberArg(name, a, 0)
+			if err != nil {
+				return nil, err
+			}
+			y, err := numberArg(name, a, 1)
+			if err != nil {
+				return nil, err
+			}
+			return fn(x, y), nil
+		},
+	})
+}
+
+// min and max work with any count of numbers - max(1, 5, 3), or a single list of them
+func extremum(fnName string, args []any, isBetter func(x, y float64) bool) (any, error) {
+	if len(args) == 1 {
+		if list, ok := args[0].(*loxList); ok {
+			args = list.elements
+		}
+	}
+	if len(args) == 0 {
+		return nil, nativeErrorf("%s() expects at least one number.", fnName)
+	}
+	var best float64
+	for position := range args {
+		num, err := numberArg(fnName, args, position)
+		if err != nil {
+			return nil, err
+		}
+		if position == 0 || isBetter(num, best) {
+			best = num
+		}
+	}
+	return best, nil
+}
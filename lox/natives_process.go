@@ -0,0 +1,52 @@
+package lox
+
+import "os"
+
+// arguments given after the script name, golox run script.lox a b c => ["a", "b", "c"]
+var scriptArgs []string
+
+func SetScriptArgs(args []string) {
+	scriptArgs = args
+}
+
+/*
+Functions to interact with the process running the script - its arguments,
+environment variables and exit code.
+*/
+func defineProcessFunctions(globals *environment) {
+	globals.define("args", nativeFunction{
+		fn: func(i interpreter, a []any) (any, error) {
+			elements := make([]any, len(scriptArgs))
+			for idx, arg := range scriptArgs {
+				elements[idx] = arg
+			}
+			return getLoxList(elements), nil
+		},
+	})
+	globals.define("getenv", nativeFunction{ // nil if the variable isn't set
+		arityCnt: 1,
+		fn: func(i interpreter, a []any) (any, error) {
+			name, err := stringArg("getenv", a, 0)
+			if err != nil {
+				return nil, err
+			}
+			if value, ok := os.LookupEnv(name); ok {
+				return value, nil
+			}
+			return nil, nil
+		},
+	})
+	globals.define("exit", nativeFunction{ // stops the script, the code is the exit code of the run
+		arityCnt: 1,
+		fn: func(i interpreter, a []any) (any, error) {
+			code, err := intArg("exit", a, 0)
+			if err != nil {
+				return nil, err
+			}
+			if code < 0 || code > 255 {
+				return nil, nativeErrorf("exit() code must be between 0 and 255.")
+			}
+			panic(exitRequest{code: code})
+		},
+	})
+}
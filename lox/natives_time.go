@@ -0,0 +1,141 @@
+package lox
+
+import (
+	"time"
+)
+
+/*
+The time module, available as the global `time` - time.now(), time.seconds(5) etc.
+Everything reads the current time from the host's clock, so a fake clock makes
+programs deterministic.
+*/
+type timeModule struct{}
+
+var _ dataType = timeModule{}
+
+func defineTimeFunctions(globals *environment) {
+	globals.define("time", timeModule{})
+}
+
+func (m timeModule) getMethod(name token) callable {
+	arityCnt, method := m.getMethodAndArity(name)
+	if method == nil {
+		return nil
+	}
+	return nativeFunction{
+		arityCnt: arityCnt,
+		fn:       method,
+		name:     name.lexeme,
+	}
+}
+
+func (m timeModule) getMethodAndArity(name token) (int, func(i interpreter, args []any) (any, error)) {
+	switch name.lexeme {
+	case "now":
+		return 0, m.now
+	case "date":
+		return variadicArity, m.date
+	case "parse":
+		return 2, m.parse
+	case "unix":
+		return 1, m.unix
+	case "duration":
+		return 1, m.duration
+	case "milliseconds":
+		return 1, m.durationOf("milliseconds", time.Millisecond)
+	case "seconds":
+		return 1, m.durationOf("seconds", time.Second)
+	case "minutes":
+		return 1, m.durationOf("minutes", time.Minute)
+	case "hours":
+		return 1, m.durationOf("hours", time.Hour)
+	case "timer":
+		return 0, m.timer
+	default:
+		return 0, nil
+	}
+}
+
+// current time in the local time zone
+func (m timeModule) now(i interpreter, args []any) (any, error) {
+	return loxTime{clock.Now()}, nil
+}
+
+// time.date(year, month, day, hour, minute, second) in the local time zone, the time parts are optional
+func (m timeModule) date(i interpreter, args []any) (any, error) {
+	if err := argCountBetween("date", args, 3, 6); err != nil {
+		return nil, err
+	}
+	parts := make([]int, 6)
+	for position := range args {
+		part, err := intArg("date", args, position)
+		if err != nil {
+			return nil, err
+		}
+		parts[position] = part
+	}
+	t := time.Date(parts[0], time.Month(parts[1]), parts[2], parts[3], parts[4], parts[5], 0, localZone())
+	return loxTime{t}, nil
+}
+
+// time.parse("2024-03-15", "DateOnly"), takes the same layouts as format
+func (m timeModule) parse(i interpreter, args []any) (any, error) {
+	str, err := stringArg("parse", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := stringArg("parse", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	t, err := time.ParseInLocation(timeLayout(layout), str, localZone())
+	if err != nil {
+		return nil, nativeErrorf("parse() can't parse '%s' with the layout '%s'.", str, layout)
+	}
+	return loxTime{t}, nil
+}
+
+// time from seconds since the unix epoch
+func (m timeModule) unix(i interpreter, args []any) (any, error) {
+	seconds, err := numberArg("unix", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return loxTime{time.UnixMilli(int64(seconds * 1000)).In(localZone())}, nil
+}
+
+// duration from a string like "1h30m" or "250ms"
+func (m timeModule) duration(i interpreter, args []any) (any, error) {
+	str, err := stringArg("duration", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return nil, nativeErrorf("duration() can't parse '%s'.", str)
+	}
+	return loxDuration{d}, nil
+}
+
+func (m timeModule) durationOf(fnName string, unit time.Duration) func(i interpreter, args []any) (any, error) {
+	return func(i interpreter, args []any) (any, error) {
+		count, err := numberArg(fnName, args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return loxDuration{time.Duration(count * float64(unit))}, nil
+	}
+}
+
+func (m timeModule) timer(i interpreter, args []any) (any, error) {
+	return &loxTimer{start: clock.Now()}, nil
+}
+
+func (m timeModule) String() string {
+	return "<module time>"
+}
+
+// the zone of the clock, a fake clock can be in any zone independent of the machine
+func localZone() *time.Location {
+	return clock.Now().Location()
+}
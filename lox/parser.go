@@ -2,6 +2,7 @@ package lox
 
 import (
 	"fmt"
+	"strings"
 )
 
 /*
@@ -80,6 +81,7 @@ func (p *parser) declaration() (stmt, *parseError) {
 }
 
 func (p *parser) vardeclaration() (stmt, *parseError) {
+	varToken := p.tokens[p.curr-1]
 	name, err := p.consumeToken(tIdentifier, "Expect variable name.")
 	if err != nil {
 		return nil, err
@@ -91,10 +93,12 @@ func (p *parser) vardeclaration() (stmt, *parseError) {
 	if err != nil {
 		return nil, err
 	}
-	err = p.eatSemicolon()
+	semicolon, err := p.consumeSemicolon()
 	return sVar{
+		keyword:     varToken,
 		name:        name,
 		initializer: e,
+		semicolon:   semicolon,
 	}, err
 }
 
@@ -102,6 +106,10 @@ func (p *parser) vardeclaration() (stmt, *parseError) {
 kind is either "function" or "method"
 */
 func (p *parser) fundeclaration(kind string) (stmt, *parseError) {
+	var keyword token
+	if kind == "function" {
+		keyword = p.tokens[p.curr-1]
+	}
 	name, err := p.consumeToken(tIdentifier, "Expect "+kind+" name.")
 	if err != nil {
 		return nil, err
@@ -134,18 +142,21 @@ func (p *parser) fundeclaration(kind string) (stmt, *parseError) {
 	if err != nil {
 		return nil, err
 	}
-	block, err := p.blockRawStmts()
+	block, end, err := p.blockRawStmts()
 	if err != nil {
 		return nil, err
 	}
 	return sFunction{
+		keyword:    keyword,
 		name:       name,
 		parameters: parameters,
 		body:       block,
+		end:        end,
 	}, nil
 }
 
 func (p *parser) classdeclaration() (stmt, *parseError) {
+	keyword := p.tokens[p.curr-1]
 	name, err := p.consumeToken(tIdentifier, "Expect class name.")
 	if err != nil {
 		return nil, err
@@ -172,14 +183,16 @@ func (p *parser) classdeclaration() (stmt, *parseError) {
 		}
 		methods = append(methods, method.(sFunction))
 	}
-	err = p.eatToken(tRightBrace, "Expect '}' after class body.")
+	end, err := p.consumeToken(tRightBrace, "Expect '}' after class body.")
 	if err != nil {
 		return nil, err
 	}
 	return sClass{
+		keyword:    keyword,
 		name:       name,
 		superclass: superclass,
 		methods:    methods,
+		end:        end,
 	}, nil
 }
 
@@ -202,24 +215,31 @@ func (p *parser) statement() (stmt, *parseError) {
 }
 
 func (p *parser) printStmt() (stmt, *parseError) {
+	printToken := p.tokens[p.curr-1]
 	expr, err := p.expression()
 	if err != nil {
 		return nil, err
 	}
-	err = p.eatSemicolon()
+	semicolon, err := p.consumeSemicolon()
 	return sPrint{
+		keyword:    printToken,
 		expression: expr,
+		semicolon:  semicolon,
 	}, err
 }
 
 func (p *parser) blockStmt() (stmt, *parseError) {
-	statements, err := p.blockRawStmts()
+	start := p.tokens[p.curr-1]
+	statements, end, err := p.blockRawStmts()
 	return sBlock{
 		statements: statements,
+		start:      start,
+		end:        end,
 	}, err
 }
 
 func (p *parser) ifStmt() (stmt, *parseError) {
+	ifToken := p.tokens[p.curr-1]
 	err := p.eatToken(tLeftParen, "Expect '(' after 'if'.")
 	if err != nil {
 		return nil, err
@@ -241,7 +261,9 @@ func (p *parser) ifStmt() (stmt, *parseError) {
 	}
 
 	var elseBranch stmt
-	if p.matchIncrement(tElse) {
+	var elseToken token
+	if p.peekMatch(tElse) {
+		elseToken = p.advance()
 		elseBranch, err = p.statement()
 		if err != nil {
 			return nil, err
@@ -249,13 +271,16 @@ func (p *parser) ifStmt() (stmt, *parseError) {
 	}
 
 	return sIf{
+		keyword:    ifToken,
 		condition:  condition,
 		thenBranch: ifBranch,
+		elseToken:  elseToken,
 		elseBranch: elseBranch,
 	}, nil
 }
 
 func (p *parser) whileStmt() (stmt, *parseError) {
+	whileToken := p.tokens[p.curr-1]
 	err := p.eatToken(tLeftParen, "Expect '(' after 'while'.")
 	if err != nil {
 		return nil, err
@@ -273,6 +298,7 @@ func (p *parser) whileStmt() (stmt, *parseError) {
 
 	body, err := p.statement()
 	return sWhile{
+		keyword:   whileToken,
 		condition: condition,
 		body:      body,
 	}, err
@@ -281,9 +307,11 @@ func (p *parser) whileStmt() (stmt, *parseError) {
 /*
 for is implemented in terms of while. a new block is created with initializer
 as the first statement. Condition is put in white condition and updater is added
-with while's body in a block attached to while's body.
+with while's body in a block attached to while's body. The loop as it's written
+is kept too, in sFor.
 */
 func (p *parser) forStmt() (stmt, *parseError) {
+	forToken := p.tokens[p.curr-1]
 	err := p.eatToken(tLeftParen, "Expect '(' after 'while'.")
 	if err != nil {
 		return nil, err
@@ -305,20 +333,19 @@ func (p *parser) forStmt() (stmt, *parseError) {
 	}
 
 	var condition expr
-	if p.matchIncrement(tSemicolon) {
-		condition = eLiteral{value: true}
-	} else {
+	if !p.matchIncrement(tSemicolon) {
 		condition, err = p.expression()
 		if err != nil {
 			return nil, err
 		}
-		err = p.eatSemicolon()
+		_, err = p.consumeSemicolon()
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	var updater expr
+	updaterStart := p.tokens[p.curr]
 	if !p.peekMatch(tRightParen) {
 		updater, err = p.expression()
 		if err != nil {
@@ -336,19 +363,30 @@ func (p *parser) forStmt() (stmt, *parseError) {
 		return nil, err
 	}
 
+	forSt := sFor{
+		keyword:     forToken,
+		initializer: initializer,
+		condition:   condition,
+		updater:     updater,
+		body:        body,
+	}
+
+	end := p.tokens[p.curr-1]
 	if updater != nil {
-		body = sBlock{[]stmt{body, sExpr{expression: updater}}}
+		body = sBlock{statements: []stmt{body, sExpr{expression: updater, start: updaterStart}}, start: forToken, end: end}
+	}
+	if condition == nil {
+		condition = eLiteral{value: true}
 	}
-	whileSt := sWhile{
+	forSt.desugared = sWhile{
+		keyword:   forToken,
 		condition: condition,
 		body:      body,
 	}
-
-	if initializer == nil {
-		return whileSt, nil
-	} else {
-		return sBlock{[]stmt{initializer, whileSt}}, nil
+	if initializer != nil {
+		forSt.desugared = sBlock{statements: []stmt{initializer, forSt.desugared}, start: forToken, end: end}
 	}
+	return forSt, nil
 }
 
 /*
@@ -365,38 +403,42 @@ func (p *parser) returnStmt() (stmt, *parseError) {
 			return nil, err
 		}
 	}
-	err = p.eatSemicolon()
+	semicolon, err := p.consumeSemicolon()
 	return sReturn{
-		keyword: returnToken,
-		value:   value,
+		keyword:   returnToken,
+		value:     value,
+		semicolon: semicolon,
 	}, err
 }
 
 /*
-gives an array of all statements in a block.
+gives an array of all statements in a block, and the "}" closing it.
 Assumes that the "{" has already been consumed.
 */
-func (p *parser) blockRawStmts() ([]stmt, *parseError) {
+func (p *parser) blockRawStmts() ([]stmt, token, *parseError) {
 	var statements []stmt
 	for !p.isAtEnd() && !p.peekMatch(tRightBrace) {
 		st, err := p.declaration()
 		if err != nil {
-			return nil, err
+			return nil, token{}, err
 		}
 		statements = append(statements, st)
 	}
-	err := p.eatToken(tRightBrace, "Expect '}' after block")
-	return statements, err
+	end, err := p.consumeToken(tRightBrace, "Expect '}' after block")
+	return statements, end, err
 }
 
 func (p *parser) exprStmt() (stmt, *parseError) {
+	start := p.tokens[p.curr]
 	expr, err := p.expression()
 	if err != nil {
 		return nil, err
 	}
-	err = p.eatSemicolon()
+	semicolon, err := p.consumeSemicolon()
 	return sExpr{
 		expression: expr,
+		start:      start,
+		semicolon:  semicolon,
 	}, err
 }
 
@@ -407,6 +449,7 @@ func (p *parser) expression() (expr, *parseError) {
 /*
 as assign is right associative, we use recursion than a loop.
 `a = b = c“ should evaluate to `a = (b = c)`
+compound assignments like `a += 2` are parsed the same way.
 */
 func (p *parser) assignment() (expr, *parseError) {
 	expr, err := p.logicOr()
@@ -414,34 +457,15 @@ func (p *parser) assignment() (expr, *parseError) {
 		return nil, err
 	}
 
-	if p.peekMatch(tEqual) {
-		equalsToken := p.tokens[p.curr]
-		p.curr++
+	if p.peekMatch(tEqual, tPlusEqual, tMinusEqual, tStarEqual, tSlashEqual, tModEqual) {
+		equalsToken := p.advance()
 		value, err := p.assignment()
 
 		if err != nil {
 			return nil, err
 		}
-		if varToken, ok := expr.(eVariable); ok {
-			return eAssign{
-				name:  varToken.name,
-				value: value,
-			}, nil
-		}
-		if getExpr, ok := expr.(eGet); ok {
-			return eSet{
-				object: getExpr.object,
-				name:   getExpr.name,
-				value:  value,
-			}, nil
-		}
-		if getIndexExpr, ok := expr.(eGetIndex); ok {
-			return eSetIndex{
-				object:  getIndexExpr.object,
-				key:     getIndexExpr.key,
-				value:   value,
-				bracket: getIndexExpr.bracket,
-			}, nil
+		if assignExpr, ok := makeAssignment(expr, equalsToken, value, false); ok {
+			return assignExpr, nil
 		}
 
 		err = parseErrorAt(equalsToken, "Invalid assignment target.")
@@ -452,6 +476,42 @@ func (p *parser) assignment() (expr, *parseError) {
 	return expr, nil
 }
 
+/*
+converts the target expression to the matching assignment node, variable access becomes
+eAssign, field access eSet and index access eSetIndex. ok is false if the target
+isn't something which can be assigned to.
+*/
+func makeAssignment(target expr, operator token, value expr, postfix bool) (expr, bool) {
+	switch target := target.(type) {
+	case eVariable:
+		return eAssign{
+			name:     target.name,
+			value:    value,
+			operator: operator,
+			postfix:  postfix,
+		}, true
+	case eGet:
+		return eSet{
+			object:   target.object,
+			name:     target.name,
+			value:    value,
+			operator: operator,
+			postfix:  postfix,
+		}, true
+	case eGetIndex:
+		return eSetIndex{
+			object:   target.object,
+			key:      target.key,
+			value:    value,
+			bracket:  target.bracket,
+			operator: operator,
+			postfix:  postfix,
+		}, true
+	default:
+		return nil, false
+	}
+}
+
 func (p *parser) logicOr() (expr, *parseError) {
 	return p.binaryOp(p.logicAnd, tOr)
 }
@@ -467,15 +527,37 @@ func (p *parser) equality() (expr, *parseError) {
 
 // ==, >=, <=, <, >
 func (p *parser) comparison() (expr, *parseError) {
-	return p.binaryOp(p.term, tGreater, tGreaterEqual, tLess, tLessEqual)
+	return p.binaryOp(p.bitOr, tGreater, tGreaterEqual, tLess, tLessEqual)
+}
+
+/*
+bitwise operators bind tighter than comparisons(unlike C), so
+"a & 1 == 0" works as expected. The order among them is | < ^ < & < shifts.
+*/
+func (p *parser) bitOr() (expr, *parseError) {
+	return p.binaryOp(p.bitXor, tBitOr)
+}
+
+func (p *parser) bitXor() (expr, *parseError) {
+	return p.binaryOp(p.bitAnd, tXor)
+}
+
+func (p *parser) bitAnd() (expr, *parseError) {
+	return p.binaryOp(p.shift, tBitAnd)
+}
+
+// <<, >>
+func (p *parser) shift() (expr, *parseError) {
+	return p.binaryOp(p.term, tShiftLeft, tShiftRight)
 }
 
 func (p *parser) term() (expr, *parseError) {
 	return p.binaryOp(p.factor, tPlus, tMinus)
 }
 
+// *, /, %, ~/
 func (p *parser) factor() (expr, *parseError) {
-	return p.binaryOp(p.unary, tSlash, tStar, tMod, tXor)
+	return p.binaryOp(p.unary, tSlash, tStar, tMod, tFloorDiv)
 }
 
 /*
@@ -493,8 +575,7 @@ func (p *parser) binaryOp(nextPrecedenceFn func() (expr, *parseError), tokens ..
 	// notice how this is also making these operators left associative
 	// as the newer op encountered on right keeps on becoming a new parent
 	for p.peekMatch(tokens...) {
-		operator := p.tokens[p.curr]
-		p.curr++
+		operator := p.advance()
 		right, err := nextPrecedenceFn()
 		if err != nil {
 			return nil, err
@@ -519,9 +600,8 @@ func (p *parser) binaryOp(nextPrecedenceFn func() (expr, *parseError), tokens ..
 }
 
 func (p *parser) unary() (expr, *parseError) {
-	if p.peekMatch(tBang, tMinus) {
-		operator := p.tokens[p.curr]
-		p.curr++
+	if p.peekMatch(tBang, tMinus, tBitNot) {
+		operator := p.advance()
 		right, err := p.unary()
 		if err != nil {
 			return nil, err
@@ -532,7 +612,92 @@ func (p *parser) unary() (expr, *parseError) {
 		}, nil
 	}
 
-	return p.call()
+	// prefix increment/decrement - ++a, --arr[0]
+	if p.peekMatch(tPlusPlus, tMinusMinus) {
+		operator := p.advance()
+		var right expr
+		var err *parseError
+		if p.peekMatch(tBang, tMinus, tBitNot, tPlusPlus, tMinusMinus) {
+			right, err = p.unary()
+		} else {
+			// the target binds tighter than "**", "--a ** 2" is (--a) ** 2
+			right, err = p.postfix()
+			if err != nil {
+				return nil, err
+			}
+			if assignExpr, ok := makeAssignment(right, operator, eLiteral{value: 1.0}, false); ok {
+				return p.exponent(assignExpr)
+			}
+			right, err = p.exponent(right)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if operator.tokenType == tMinusMinus {
+			/*
+				before "--" was an operator "--(3)" meant negating twice, and the lox tests
+				still expect it to. There is no unary "+", so "++(3)" has no such meaning
+				and is an error.
+			*/
+			minus := operator
+			minus.tokenType = tMinus
+			minus.lexeme = "-"
+			minus.column = minus.startCol + 1
+			return eUnary{operator: minus, right: eUnary{operator: minus, right: right}}, nil
+		}
+		logParseError(operator, parseErrorAt(operator, "Invalid assignment target.").msg)
+		return right, nil
+	}
+
+	return p.power()
+}
+
+/*
+exponentiation binds tighter than unary operators on its left, so -2 ** 2 is -(2 ** 2).
+It's right associative, 2 ** 3 ** 2 is 2 ** (3 ** 2). So unlike binaryOp, we recurse
+for the right operand instead of looping. Going back to unary for the right operand
+also allows 2 ** -1.
+*/
+func (p *parser) power() (expr, *parseError) {
+	expr, err := p.postfix()
+	if err != nil {
+		return nil, err
+	}
+	return p.exponent(expr)
+}
+
+// the "** right" after the left operand of power, if there is one
+func (p *parser) exponent(left expr) (expr, *parseError) {
+	if p.peekMatch(tStarStar) {
+		operator := p.advance()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return eBinary{
+			left:     left,
+			operator: operator,
+			right:    right,
+		}, nil
+	}
+	return left, nil
+}
+
+// postfix increment/decrement - a++, obj.count--
+func (p *parser) postfix() (expr, *parseError) {
+	expr, err := p.call()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peekMatch(tPlusPlus, tMinusMinus) {
+		operator := p.advance()
+		if assignExpr, ok := makeAssignment(expr, operator, eLiteral{value: 1.0}, true); ok {
+			return assignExpr, nil
+		}
+		logParseError(operator, parseErrorAt(operator, "Invalid assignment target.").msg)
+	}
+	return expr, nil
 }
 
 /*
@@ -565,7 +730,7 @@ func (p *parser) call() (expr, *parseError) {
 				name:   field,
 			}
 		} else if p.matchIncrement(tLeftBracket) {
-			index, err := p.expression()
+			index, err := p.indexOrSlice()
 			if err != nil {
 				return nil, err
 			}
@@ -585,6 +750,38 @@ func (p *parser) call() (expr, *parseError) {
 	return expr, nil
 }
 
+/*
+the key inside brackets, either a single index like arr[2] or a python like slice
+arr[start:end:step] where all three parts are optional - arr[1:], arr[::-1]
+Assumes that the "[" has already been consumed.
+*/
+func (p *parser) indexOrSlice() (expr, *parseError) {
+	var start expr
+	var err *parseError
+	if !p.peekMatch(tColon) {
+		start, err = p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peekMatch(tColon) {
+			return start, nil
+		}
+	}
+
+	slice := eSlice{start: start, colon: p.advance()} // consume the ":"
+	if !p.peekMatch(tColon, tRightBracket) {
+		if slice.end, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+	if p.matchIncrement(tColon) && !p.peekMatch(tRightBracket) {
+		if slice.step, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+	return slice, nil
+}
+
 func (p *parser) finishCall(callee expr) (expr, *parseError) {
 	var arguments []expr
 	hasMore := !p.peekMatch(tRightParen)
@@ -614,33 +811,35 @@ func (p *parser) finishCall(callee expr) (expr, *parseError) {
 	}, nil
 }
 
-func (p *parser) list_display() ([]expr, *parseError) {
+// gives the elements and the "]" closing the list
+func (p *parser) list_display() ([]expr, token, *parseError) {
 	var exprList []expr
 	for !p.peekMatch(tRightBracket) {
 		expr, err := p.expression()
 		if err != nil {
-			return nil, err
+			return nil, token{}, err
 		}
 		exprList = append(exprList, expr)
 		p.matchIncrement(tComma)
 	}
-	err := p.eatToken(tRightBracket, "Expect ']' after list.")
-	return exprList, err
+	end, err := p.consumeToken(tRightBracket, "Expect ']' after list.")
+	return exprList, end, err
 }
 
 func (p *parser) primary() (expr, *parseError) {
-	token := p.tokens[p.curr]
-	p.curr++
+	token := p.advance()
 
 	switch token.tokenType {
 	case tTrue:
-		return eLiteral{value: true}, nil
+		return eLiteral{value: true, token: token}, nil
 	case tFalse:
-		return eLiteral{value: false}, nil
+		return eLiteral{value: false, token: token}, nil
 	case tNil:
-		return eLiteral{value: nil}, nil
+		return eLiteral{value: nil, token: token}, nil
 	case tNumber, tString:
-		return eLiteral{value: token.literal}, nil
+		return eLiteral{value: token.literal, token: token}, nil
+	case tInterpolation:
+		return p.template(token)
 	case tLeftParen:
 		expr, err := p.expression()
 		if err != nil {
@@ -648,15 +847,15 @@ func (p *parser) primary() (expr, *parseError) {
 		} else if !p.peekMatch(tRightParen) {
 			return nil, parseErrorAt(p.tokens[p.curr], "Expect ')' after expression.")
 		} else {
-			p.curr++ // consume the right paren
-			return eGrouping{expression: expr}, nil
+			p.advance() // consume the right paren
+			return eGrouping{expression: expr, open: token, close: p.tokens[p.curr-1]}, nil
 		}
 	case tLeftBracket:
-		exprList, err := p.list_display()
+		exprList, end, err := p.list_display()
 		if err != nil {
 			return nil, err
 		}
-		return eList{elements: exprList}, nil
+		return eList{elements: exprList, open: token, close: end}, nil
 	case tThis:
 		return eThis{keyword: token}, nil
 	case tSuper:
@@ -683,6 +882,55 @@ func (p *parser) primary() (expr, *parseError) {
 	}
 }
 
+/*
+the scanner breaks "a ${b} c ${d}" into INTERPOLATION("a ") b INTERPOLATION(" c ") d STRING("").
+Assumes that the first interpolation token has already been consumed.
+*/
+func (p *parser) template(start token) (expr, *parseError) {
+	var parts []expr
+	addStringPart := func(str token) {
+		if str.literal != "" {
+			parts = append(parts, eLiteral{value: str.literal, token: str})
+		}
+	}
+	addStringPart(start)
+	for {
+		// the rest of the string starts with the "}", so "${}" is missing the expression
+		if next := p.tokens[p.curr]; (next.tokenType == tString || next.tokenType == tInterpolation) && strings.HasPrefix(next.lexeme, "}") {
+			brace := next
+			brace.lexeme, brace.line, brace.column = "}", next.startLine, next.startCol+1
+			return nil, parseErrorAt(brace, "Expect expression.")
+		}
+		expr, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, expr)
+
+		next := p.tokens[p.curr]
+		switch next.tokenType {
+		case tInterpolation:
+			p.advance()
+			addStringPart(next)
+		case tString:
+			p.advance()
+			addStringPart(next)
+			return eTemplate{parts: parts, start: start, end: next}, nil
+		default:
+			return nil, parseErrorAt(next, "Expect '}' after interpolated expression.")
+		}
+	}
+}
+
+// gives the current token and moves to the next one, the EOF is never moved past
+func (p *parser) advance() token {
+	token := p.tokens[p.curr]
+	if token.tokenType != tEof {
+		p.curr++
+	}
+	return token
+}
+
 func (p *parser) isAtEnd() bool {
 	return p.tokens[p.curr].tokenType == tEof
 }
@@ -695,20 +943,20 @@ func (p *parser) consumeCascadingErrors() {
 			return
 		}
 		if p.peekMatch(tSemicolon) {
-			p.curr++
+			p.advance()
 			return
 		}
 
-		p.curr++
+		p.advance()
 	}
 }
 
 /*
-semicolons must be present at the end of every statement, but
-we don't actually care about them
+semicolons must be present at the end of every statement, they're
+kept only for where the statement ends
 */
-func (p *parser) eatSemicolon() *parseError {
-	return p.eatToken(tSemicolon, "Expect ';' after expression.")
+func (p *parser) consumeSemicolon() (token, *parseError) {
+	return p.consumeToken(tSemicolon, "Expect ';' after expression.")
 }
 
 /*
@@ -0,0 +1,26 @@
+package lox
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// generator used by randInt and random, randomly seeded unless the host sets a seed
+var rng = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+
+func SetRandomSeed(seed uint64) {
+	rng = rand.New(rand.NewPCG(seed, seed))
+}
+
+// the time a fake clock starts at in deterministic mode
+var deterministicStart = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+/*
+makes every run of a program give the same output, so programs using random
+numbers or time can be tested. Random numbers come from the seed, and time is
+virtual - it starts at 2000-01-01 UTC and sleep advances it without waiting.
+*/
+func SetDeterministic(seed uint64) {
+	SetRandomSeed(seed)
+	SetClock(NewFakeClock(deterministicStart))
+}
@@ -1,5 +1,7 @@
 package lox
 
+import "fmt"
+
 // enum to track if we're inside a function
 type functionType int
 
@@ -31,6 +33,7 @@ type resolver struct {
 	interpreter  *interpreter
 	currFunction functionType
 	currClass    classType
+	symbols      *symbolTable // records the names for editor tooling, nil when running a program
 }
 
 var _ exprVisitor = (*resolver)(nil)
@@ -53,9 +56,9 @@ func (r *resolver) resolveStmts(stmts []stmt) {
 	for _, s := range stmts {
 		if err := r.resolveStmt(s); err != nil {
 			if pErr, ok := err.(*parseError); ok {
-				logParseError(pErr.token, pErr.msg)
+				logResolveError(pErr.token, pErr.msg)
 			} else {
-				logParseError(token{}, err.Error())
+				logResolveError(token{}, err.Error())
 			}
 		}
 	}
@@ -63,6 +66,7 @@ func (r *resolver) resolveStmts(stmts []stmt) {
 
 func (r *resolver) visitBlockStmt(stmt sBlock) error {
 	r.beginScope()
+	r.symbols.scopeEndsAt(stmt.end)
 	r.resolveStmts(stmt.statements)
 	r.endScope()
 	return nil
@@ -83,6 +87,7 @@ func (r *resolver) visitVarStmt(stmt sVar) error {
 	if err := r.declare(stmt.name); err != nil {
 		return err
 	}
+	r.symbols.declare(stmt.name, stmt.span(), SymbolVariable, "var "+stmt.name.lexeme)
 	if stmt.initializer != nil {
 		// the initializer can't reference the variable which is being declared
 		// for e.g. var a = a + 1; is invalid
@@ -105,6 +110,7 @@ for e.g. a + b;
 */
 func (r *resolver) visitVariableExpr(expr eVariable) (any, error) {
 	varName := expr.name.lexeme
+	r.symbols.reference(expr.name)
 	if len(r.scopes) != 0 {
 		if isReady, exists := r.peekScope()[varName]; exists && !isReady {
 			return nil, parseErrorAt(expr.name, "Can't read local variable in its own initializer.")
@@ -118,6 +124,7 @@ func (r *resolver) visitAssignExpr(expr eAssign) (any, error) {
 	if _, err := r.resolveExpr(expr.value); err != nil {
 		return nil, err
 	}
+	r.symbols.reference(expr.name)
 	r.resolveLocal(expr.name)
 	return nil, nil
 }
@@ -129,9 +136,35 @@ func (r *resolver) visitBinaryExpr(expr eBinary) (any, error) {
 	if _, err := r.resolveExpr(expr.right); err != nil {
 		return nil, err
 	}
+	if expr.operator.tokenType == tPlus {
+		r.checkStringCoercion(expr)
+	}
 	return nil, nil
 }
 
+/*
+"+" with a string converts the other side to a string, which is likely a mistake
+when it's nil, a boolean or a list, like "a" + nil giving "anil". Numbers are
+often meant to be converted, but a template string like "${n}" shows it more clearly.
+*/
+func (r *resolver) checkStringCoercion(expr eBinary) {
+	left, right := literalType(expr.left), literalType(expr.right)
+	other := right
+	if right == "string" {
+		other = left
+	} else if left != "string" {
+		return
+	}
+	if other == "" || other == "string" {
+		return
+	}
+	severity := SeverityWarning
+	if other == "number" {
+		severity = SeverityInfo
+	}
+	logWarning(expr.operator, severity, WarnStringCoercion, fmt.Sprintf("The %s is implicitly converted to a string by '+'.", other))
+}
+
 func (r *resolver) visitCallExpr(expr eCall) (any, error) {
 	if _, err := r.resolveExpr(expr.callee); err != nil {
 		return nil, err
@@ -174,6 +207,27 @@ func (r *resolver) visitSetIndexExpr(expr eSetIndex) (any, error) {
 	return nil, err
 }
 
+func (r *resolver) visitSliceExpr(slice eSlice) (any, error) {
+	for _, part := range []expr{slice.start, slice.end, slice.step} {
+		if part == nil {
+			continue
+		}
+		if _, err := r.resolveExpr(part); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (r *resolver) visitTemplateExpr(expr eTemplate) (any, error) {
+	for _, part := range expr.parts {
+		if _, err := r.resolveExpr(part); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
 func (r *resolver) visitGroupingExpr(expr eGrouping) (any, error) {
 	return r.resolveExpr(expr.expression)
 }
@@ -235,6 +289,7 @@ func (r *resolver) visitFunctionStmt(stmt sFunction) error {
 	}
 	// we define right away, as it's legal for the function to reference itself for recursion
 	r.define(stmt.name.lexeme)
+	r.symbols.declare(stmt.name, stmt.span(), SymbolFunction, functionDetail(stmt, ""))
 
 	err := r.resolveFunction(stmt, fFunction)
 	return err
@@ -278,6 +333,10 @@ func (r *resolver) visitIfStmt(stmt sIf) error {
 	return nil
 }
 
+func (r *resolver) visitForStmt(stmt sFor) error {
+	return r.resolveStmt(stmt.desugared)
+}
+
 func (r *resolver) visitWhileStmt(stmt sWhile) error {
 	if _, err := r.resolveExpr(stmt.condition); err != nil {
 		return err
@@ -299,6 +358,9 @@ func (r *resolver) visitClassStmt(stmt sClass) error {
 		return err
 	}
 	r.define(stmt.name.lexeme)
+	r.symbols.declare(stmt.name, stmt.span(), SymbolClass, classDetail(stmt))
+	r.symbols.enter()
+	defer r.symbols.leave()
 
 	if stmt.superclass != nil {
 		r.currClass = cSubClass
@@ -320,6 +382,7 @@ func (r *resolver) visitClassStmt(stmt sClass) error {
 		if method.name.lexeme == "init" {
 			declarationType = fInitializer
 		}
+		r.symbols.declare(method.name, method.span(), SymbolMethod, functionDetail(method, stmt.name.lexeme))
 		if err := r.resolveFunction(method, declarationType); err != nil {
 			return err
 		}
@@ -331,17 +394,21 @@ func (r *resolver) visitClassStmt(stmt sClass) error {
 func (r *resolver) resolveFunction(function sFunction, funcType functionType) error {
 	enclosingFunction := r.currFunction
 	r.currFunction = funcType
+	r.symbols.enter()
 	defer func() {
 		r.currFunction = enclosingFunction
 		r.endScope()
+		r.symbols.leave()
 	}()
 
 	r.beginScope()
+	r.symbols.scopeEndsAt(function.end)
 	for _, param := range function.parameters {
 		if err := r.declare(param); err != nil {
 			return err
 		}
 		r.define(param.lexeme)
+		r.symbols.declare(param, tokenSpan(param), SymbolParameter, "param "+param.lexeme)
 	}
 	r.resolveStmts(function.body)
 	return nil
@@ -349,6 +416,7 @@ func (r *resolver) resolveFunction(function sFunction, funcType functionType) er
 
 func (r *resolver) beginScope() {
 	r.scopes = append(r.scopes, make(map[string]bool))
+	r.symbols.beginScope()
 }
 
 func (r *resolver) endScope() {
@@ -356,6 +424,7 @@ func (r *resolver) endScope() {
 		return
 	}
 	r.scopes = r.scopes[:len(r.scopes)-1]
+	r.symbols.endScope()
 }
 
 /*
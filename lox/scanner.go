@@ -2,6 +2,7 @@ package lox
 
 import (
 	"strconv"
+	"strings"
 )
 
 /**
@@ -15,30 +16,49 @@ type scanner struct {
 	tokens []token
 
 	// to keep track of where we're in scanning
-	start    int // start index of current lexeme
-	curr     int // curr index we're at
-	line     int // the line we're at
-	lineChar int // the char we're at on the current line
+	start     int // start index of current lexeme
+	curr      int // curr index we're at
+	line      int // the line we're at
+	lineStart int // index of the first char of the current line
+
+	// where the current lexeme starts, as it can end on a later line for multiline strings
+	startLine   int
+	startColumn int
+
+	// for every "${" we're inside of, the count of "{" opened but not yet closed
+	// within the embedded expression. This is to know which "}" resumes the string.
+	interpolations []int
+
+	// comments and blank lines since the last token, attached to the next token as its trivia
+	comments      []comment
+	newlines      int
+	lastTokenLine int
 }
 
 func createScanner(source string) *scanner {
 	return &scanner{
-		source:   source,
-		tokens:   []token{},
-		start:    0,
-		curr:     0,
-		line:     1,
-		lineChar: 1,
+		source:    source,
+		tokens:    []token{},
+		start:     0,
+		curr:      0,
+		line:      1,
+		lineStart: 0,
 	}
 }
 
 func (s *scanner) scanTokens() []token {
 	for !s.isAtEnd() {
 		s.start = s.curr
+		s.startLine, s.startColumn = s.line, s.column()
 		s.scanNextToken()
 	}
 
-	s.tokens = append(s.tokens, makeEOFToken(s.line, 0))
+	if len(s.interpolations) > 0 {
+		logScanError(s.line, s.column(), "Error: Unterminated string.")
+	}
+	eof := makeEOFToken(len(s.source), s.line, s.column())
+	eof.trivia = s.takeTrivia()
+	s.tokens = append(s.tokens, eof)
 	return s.tokens
 }
 
@@ -51,8 +71,20 @@ func (s *scanner) scanNextToken() {
 	case ')':
 		s.addSimpleToken(tRightParen)
 	case '{':
+		if depth := len(s.interpolations); depth > 0 {
+			s.interpolations[depth-1]++
+		}
 		s.addSimpleToken(tLeftBrace)
 	case '}':
+		if depth := len(s.interpolations); depth > 0 {
+			if s.interpolations[depth-1] == 0 {
+				// end of the embedded expression, continue with rest of the string
+				s.interpolations = s.interpolations[:depth-1]
+				s.scanString()
+				return
+			}
+			s.interpolations[depth-1]--
+		}
 		s.addSimpleToken(tRightBrace)
 	case '[':
 		s.addSimpleToken(tLeftBracket)
@@ -60,39 +92,69 @@ func (s *scanner) scanNextToken() {
 		s.addSimpleToken(tRightBracket)
 	case ',':
 		s.addSimpleToken(tComma)
+	case ':':
+		s.addSimpleToken(tColon)
 	case '.':
 		s.addSimpleToken(tDot)
 	case '-':
-		s.addSimpleToken(tMinus)
+		s.addIncrementToken(tMinus, tMinusEqual, tMinusMinus)
 	case '+':
-		s.addSimpleToken(tPlus)
+		s.addIncrementToken(tPlus, tPlusEqual, tPlusPlus)
 	case ';':
 		s.addSimpleToken(tSemicolon)
 	case '/':
 		if s.peek() == '/' {
-			for !s.isAtEnd() && s.peek() != '\n' { // ignore comments
+			for !s.isAtEnd() && s.peek() != '\n' {
 				s.advance()
 			}
+			s.addComment()
 		} else {
-			s.addSimpleToken(tSlash)
+			s.addConditionalToken(tSlash, tSlashEqual)
 		}
 	case '*':
-		s.addSimpleToken(tStar)
+		if s.peek() == '*' {
+			s.advance()
+			s.addSimpleToken(tStarStar)
+		} else {
+			s.addConditionalToken(tStar, tStarEqual)
+		}
 	case '%':
-		s.addSimpleToken(tMod)
+		s.addConditionalToken(tMod, tModEqual)
 	case '^':
 		s.addSimpleToken(tXor)
+	case '&':
+		s.addSimpleToken(tBitAnd)
+	case '|':
+		s.addSimpleToken(tBitOr)
+	case '~':
+		// "//" is a comment, so floor division is "~/"
+		if s.peek() == '/' {
+			s.advance()
+			s.addSimpleToken(tFloorDiv)
+		} else {
+			s.addSimpleToken(tBitNot)
+		}
 	case ' ', '\t', '\r':
 		// ignore whitespace
 	case '\n':
-		s.line++
-		s.lineChar = 1
+		s.newLine()
+		s.newlines++
 	case '!':
 		s.addConditionalToken(tBang, tBangEqual)
 	case '<':
-		s.addConditionalToken(tLess, tLessEqual)
+		if s.peek() == '<' {
+			s.advance()
+			s.addSimpleToken(tShiftLeft)
+		} else {
+			s.addConditionalToken(tLess, tLessEqual)
+		}
 	case '>':
-		s.addConditionalToken(tGreater, tGreaterEqual)
+		if s.peek() == '>' {
+			s.advance()
+			s.addSimpleToken(tShiftRight)
+		} else {
+			s.addConditionalToken(tGreater, tGreaterEqual)
+		}
 	case '=':
 		s.addConditionalToken(tEqual, tEqualEqual)
 	case '"':
@@ -103,34 +165,68 @@ func (s *scanner) scanNextToken() {
 		} else if isAlpha(c) {
 			s.scanIdentifier()
 		} else {
-			logScanError(s.line, s.lineChar, "Error: Unexpected character.")
+			logScanError(s.line, s.column(), "Error: Unexpected character.")
 		}
 	}
 }
 
 func (s *scanner) advance() {
 	s.curr++
-	s.lineChar++
 }
 
+// called after advancing past a "\n"
+func (s *scanner) newLine() {
+	s.line++
+	s.lineStart = s.curr
+}
+
+// column of the char we're at on the current line, starting at 1
+func (s *scanner) column() int {
+	return s.curr - s.lineStart + 1
+}
+
+/*
+scans from after the opening quote(or the "}" ending an interpolation) till the
+closing quote. If an embedded expression "${" is found on the way, the part so far
+is added as an interpolation token, and we go back to scanning normal tokens for the
+expression. For e.g. "a ${b} c" becomes INTERPOLATION("a ") IDENTIFIER(b) STRING(" c").
+"\${" is a literal "${", the backslash is dropped from the value.
+*/
 func (s *scanner) scanString() {
+	valueStart := s.curr
 	for !s.isAtEnd() && s.peek() != '"' {
+		if s.peek() == '\\' && s.peekNext() == '$' {
+			s.advance() // skip the \, the $ is skipped below so it isn't an interpolation
+		} else if s.peek() == '$' && s.peekNext() == '{' {
+			value := unescapeString(s.source[valueStart:s.curr])
+			s.advance() // skip the $
+			s.advance() // skip the {
+			s.interpolations = append(s.interpolations, 0)
+			s.addToken(tInterpolation, value)
+			return
+		}
 		s.advance()
-		if s.peek() == '\n' { // strings can be multiline
-			s.line++
+		if s.source[s.curr-1] == '\n' { // strings can be multiline
+			s.newLine()
 		}
 	}
 
 	if s.isAtEnd() {
-		logScanError(s.line, s.lineChar, "Error: Unterminated string.")
+		logScanError(s.line, s.column(), "Error: Unterminated string.")
+		// the strings this one is embedded in are unterminated too, it's the same error
+		s.interpolations = nil
 		return
 	}
 
 	s.advance() // skip the closing "
-	value := s.source[s.start+1 : s.curr-1]
+	value := unescapeString(s.source[valueStart : s.curr-1])
 	s.addToken(tString, value)
 }
 
+func unescapeString(raw string) string {
+	return strings.ReplaceAll(raw, `\$`, "$")
+}
+
 // scan numbers like 1,2, 3.53, etc
 func (s *scanner) scanNumber() {
 	for isDigit(s.peek()) {
@@ -148,7 +244,7 @@ func (s *scanner) scanNumber() {
 	num_str := s.source[s.start:s.curr]
 	num, err := strconv.ParseFloat(num_str, 64)
 	if err != nil {
-		logScanError(s.line, s.lineChar, "Error: "+err.Error())
+		logScanError(s.line, s.column(), "Error: "+err.Error())
 		return
 	}
 	s.addToken(tNumber, num)
@@ -169,7 +265,7 @@ func (s *scanner) scanIdentifier() {
 	}
 }
 
-// these are the characters - !,<,>,= which token type they become depends on if the next character is =
+// these are the characters - !,<,>,=,/,*,% which token type they become depends on if the next character is =
 func (s *scanner) addConditionalToken(solo, withEqual TokenType) {
 	if s.isAtEnd() || s.source[s.curr] != '=' {
 		s.addSimpleToken(solo)
@@ -179,6 +275,16 @@ func (s *scanner) addConditionalToken(solo, withEqual TokenType) {
 	}
 }
 
+// these are + and -, which can also be doubled(++, --) or followed by = (+=, -=)
+func (s *scanner) addIncrementToken(solo, withEqual, doubled TokenType) {
+	if !s.isAtEnd() && s.source[s.curr] == s.source[s.start] {
+		s.advance()
+		s.addSimpleToken(doubled)
+	} else {
+		s.addConditionalToken(solo, withEqual)
+	}
+}
+
 func (s *scanner) addSimpleToken(tokenType TokenType) {
 	s.addToken(tokenType, nil)
 }
@@ -188,9 +294,38 @@ func (s *scanner) addToken(tokenType TokenType, literal interface{}) {
 		tokenType: tokenType,
 		lexeme:    s.source[s.start:s.curr],
 		literal:   literal,
+		offset:    s.start,
+		startLine: s.startLine,
+		startCol:  s.startColumn,
 		line:      s.line,
-		column:    s.lineChar,
+		column:    s.column(),
+		trivia:    s.takeTrivia(),
 	})
+	s.lastTokenLine = s.line
+}
+
+/*
+comments don't affect the program, but are kept for tools like the formatter which
+have to reproduce them
+*/
+func (s *scanner) addComment() {
+	s.comments = append(s.comments, comment{
+		text:            s.source[s.start:s.curr],
+		line:            s.line,
+		ownLine:         s.lastTokenLine != s.line || len(s.tokens) == 0,
+		blankLineBefore: s.newlines > 1,
+	})
+	s.newlines = 0
+}
+
+// the comments and blank lines before the token being added, nil if there are none
+func (s *scanner) takeTrivia() *trivia {
+	var t *trivia
+	if len(s.comments) > 0 || s.newlines > 1 {
+		t = &trivia{comments: s.comments, blankLineBefore: s.newlines > 1}
+	}
+	s.comments, s.newlines = nil, 0
+	return t
 }
 
 // get the next character safely
@@ -0,0 +1,181 @@
+package lox
+
+/*
+Every node of the AST knows the range of the source it was parsed from, so tools
+can point at all of "a.b(c)" rather than only where an error is reported.
+*/
+
+// a place in the source, lines and columns start at 1. Offsets and columns count bytes.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+// End is just after the last character, a multiline string ends on a later line than it starts
+type Span struct {
+	Start Position
+	End   Position
+}
+
+func tokenSpan(t token) Span {
+	if t.line == 0 {
+		return Span{}
+	}
+	return Span{
+		Start: Position{Offset: t.offset, Line: t.startLine, Column: t.startColumn()},
+		End:   Position{Offset: t.offset + len(t.lexeme), Line: t.line, Column: t.column},
+	}
+}
+
+// the zero span is of nodes made by the parser, like the condition "true" of "for (;;)"
+func (s Span) isZero() bool {
+	return s.Start.Line == 0
+}
+
+// from the start of s till the end of end, zero spans are skipped
+func (s Span) to(end Span) Span {
+	if s.isZero() {
+		return end
+	}
+	if end.isZero() {
+		return s
+	}
+	return Span{Start: s.Start, End: end.End}
+}
+
+/*
+"a++" and "++a" have the operator on either side of the target, other
+assignments end with the value
+*/
+func assignSpan(target Span, operator token, value expr, postfix bool) Span {
+	if operator.tokenType != tPlusPlus && operator.tokenType != tMinusMinus {
+		return target.to(value.span())
+	}
+	if postfix {
+		return target.to(tokenSpan(operator))
+	}
+	return tokenSpan(operator).to(target)
+}
+
+func (e eAssign) span() Span {
+	return assignSpan(tokenSpan(e.name), e.operator, e.value, e.postfix)
+}
+
+func (e eBinary) span() Span {
+	return e.left.span().to(e.right.span())
+}
+
+func (e eCall) span() Span {
+	return e.callee.span().to(tokenSpan(e.paren))
+}
+
+func (e eGet) span() Span {
+	return e.object.span().to(tokenSpan(e.name))
+}
+
+func (e eGrouping) span() Span {
+	return tokenSpan(e.open).to(tokenSpan(e.close))
+}
+
+func (e eLiteral) span() Span {
+	return tokenSpan(e.token)
+}
+
+func (e eLogical) span() Span {
+	return e.left.span().to(e.right.span())
+}
+
+func (e eSet) span() Span {
+	return assignSpan(e.object.span().to(tokenSpan(e.name)), e.operator, e.value, e.postfix)
+}
+
+func (e eSuper) span() Span {
+	return tokenSpan(e.keyword).to(tokenSpan(e.method))
+}
+
+func (e eThis) span() Span {
+	return tokenSpan(e.keyword)
+}
+
+func (e eUnary) span() Span {
+	return tokenSpan(e.operator).to(e.right.span())
+}
+
+func (e eVariable) span() Span {
+	return tokenSpan(e.name)
+}
+
+func (e eList) span() Span {
+	return tokenSpan(e.open).to(tokenSpan(e.close))
+}
+
+func (e eGetIndex) span() Span {
+	return e.object.span().to(tokenSpan(e.bracket))
+}
+
+func (e eSetIndex) span() Span {
+	return assignSpan(e.object.span().to(tokenSpan(e.bracket)), e.operator, e.value, e.postfix)
+}
+
+func (e eTemplate) span() Span {
+	return tokenSpan(e.start).to(tokenSpan(e.end))
+}
+
+// the brackets aren't part of a slice, it's from its first part till its last one
+func (e eSlice) span() Span {
+	span := tokenSpan(e.colon)
+	if e.start != nil {
+		span = e.start.span().to(span)
+	}
+	for _, part := range []expr{e.end, e.step} {
+		if part != nil {
+			span = span.to(part.span())
+		}
+	}
+	return span
+}
+
+func (s sExpr) span() Span {
+	return s.expression.span().to(tokenSpan(s.semicolon))
+}
+
+func (s sPrint) span() Span {
+	return tokenSpan(s.keyword).to(tokenSpan(s.semicolon))
+}
+
+func (s sVar) span() Span {
+	return tokenSpan(s.keyword).to(tokenSpan(s.semicolon))
+}
+
+func (s sBlock) span() Span {
+	return tokenSpan(s.start).to(tokenSpan(s.end))
+}
+
+func (s sIf) span() Span {
+	if s.elseBranch != nil {
+		return tokenSpan(s.keyword).to(s.elseBranch.span())
+	}
+	return tokenSpan(s.keyword).to(s.thenBranch.span())
+}
+
+func (s sWhile) span() Span {
+	return tokenSpan(s.keyword).to(s.body.span())
+}
+
+func (s sFor) span() Span {
+	return tokenSpan(s.keyword).to(s.body.span())
+}
+
+// methods have no "fun", they start at their name
+func (s sFunction) span() Span {
+	return tokenSpan(s.keyword).to(tokenSpan(s.name)).to(tokenSpan(s.end))
+}
+
+func (s sReturn) span() Span {
+	return tokenSpan(s.keyword).to(tokenSpan(s.semicolon))
+}
+
+func (s sClass) span() Span {
+	return tokenSpan(s.keyword).to(tokenSpan(s.end))
+}
@@ -0,0 +1,52 @@
+package lox
+
+import "slices"
+
+/*
+the candidate closest to the name by edit distance, for suggesting what was meant
+by an undefined name. It's "" when none is close enough to be a likely typo, which
+is at most a third of the name's characters changed.
+*/
+func closestName(name string, candidates []string) string {
+	candidates = slices.Clone(candidates)
+	slices.Sort(candidates) // the same suggestion every time for ties
+	closest, closestDistance := "", max(1, len(name)/3)+1
+	for _, candidate := range candidates {
+		if candidate == name {
+			continue
+		}
+		if distance := editDistance(name, candidate); distance < closestDistance && distance < len(name) {
+			closest, closestDistance = candidate, distance
+		}
+	}
+	return closest
+}
+
+/*
+the number of characters to insert, delete or replace to change a to b, where swapping
+two adjacent characters counts as one change as it's a common typo
+*/
+func editDistance(a string, b string) int {
+	// rows of the distances from the prefixes of a to those of b, the last 2 are needed for swaps
+	prevPrev := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prevPrev[j-2]+1)
+			}
+		}
+		prevPrev, prev, curr = prev, curr, prevPrev
+	}
+	return prev[len(b)]
+}
@@ -19,6 +19,7 @@ const (
 	tLeftBracket
 	tRightBracket
 	tComma
+	tColon
 	tDot
 	tMinus
 	tPlus
@@ -27,6 +28,9 @@ const (
 	tStar
 	tMod
 	tXor
+	tBitAnd
+	tBitOr
+	tBitNot
 
 	// conditions(1 or 2 char) tokens
 	tBang
@@ -37,10 +41,22 @@ const (
 	tGreaterEqual
 	tLess
 	tLessEqual
+	tPlusEqual
+	tMinusEqual
+	tStarEqual
+	tSlashEqual
+	tModEqual
+	tPlusPlus
+	tMinusMinus
+	tShiftLeft
+	tShiftRight
+	tFloorDiv
+	tStarStar
 
 	// literals
 	tIdentifier
 	tString
+	tInterpolation // string part before an embedded "${" expression
 	tNumber
 
 	// keywords
@@ -65,49 +81,64 @@ const (
 )
 
 var tokenNames = map[TokenType]string{
-	tLeftParen:    "LEFT_PAREN",
-	tRightParen:   "RIGHT_PAREN",
-	tLeftBrace:    "LEFT_BRACE",
-	tRightBrace:   "RIGHT_BRACE",
-	tLeftBracket:  "LEFT_BRACKET",
-	tRightBracket: "RIGHT_BRACKET",
-	tComma:        "COMMA",
-	tDot:          "DOT",
-	tMinus:        "MINUS",
-	tPlus:         "PLUS",
-	tSemicolon:    "SEMICOLON",
-	tSlash:        "SLASH",
-	tStar:         "STAR",
-	tMod:          "MOD",
-	tXor:          "XOR",
-	tBang:         "BANG",
-	tBangEqual:    "BANG_EQUAL",
-	tEqual:        "EQUAL",
-	tEqualEqual:   "EQUAL_EQUAL",
-	tGreater:      "GREATER",
-	tGreaterEqual: "GREATER_EQUAL",
-	tLess:         "LESS",
-	tLessEqual:    "LESS_EQUAL",
-	tIdentifier:   "IDENTIFIER",
-	tString:       "STRING",
-	tNumber:       "NUMBER",
-	tAnd:          "AND",
-	tClass:        "CLASS",
-	tElse:         "ELSE",
-	tFalse:        "FALSE",
-	tFun:          "FUN",
-	tFor:          "FOR",
-	tIf:           "IF",
-	tNil:          "NIL",
-	tOr:           "OR",
-	tPrint:        "PRINT",
-	tReturn:       "RETURN",
-	tSuper:        "SUPER",
-	tThis:         "THIS",
-	tTrue:         "TRUE",
-	tVar:          "VAR",
-	tWhile:        "WHILE",
-	tEof:          "EOF",
+	tLeftParen:     "LEFT_PAREN",
+	tRightParen:    "RIGHT_PAREN",
+	tLeftBrace:     "LEFT_BRACE",
+	tRightBrace:    "RIGHT_BRACE",
+	tLeftBracket:   "LEFT_BRACKET",
+	tRightBracket:  "RIGHT_BRACKET",
+	tComma:         "COMMA",
+	tDot:           "DOT",
+	tMinus:         "MINUS",
+	tPlus:          "PLUS",
+	tSemicolon:     "SEMICOLON",
+	tSlash:         "SLASH",
+	tStar:          "STAR",
+	tMod:           "MOD",
+	tXor:           "XOR",
+	tBitAnd:        "BIT_AND",
+	tBitOr:         "BIT_OR",
+	tBitNot:        "BIT_NOT",
+	tBang:          "BANG",
+	tBangEqual:     "BANG_EQUAL",
+	tEqual:         "EQUAL",
+	tEqualEqual:    "EQUAL_EQUAL",
+	tGreater:       "GREATER",
+	tGreaterEqual:  "GREATER_EQUAL",
+	tLess:          "LESS",
+	tLessEqual:     "LESS_EQUAL",
+	tPlusEqual:     "PLUS_EQUAL",
+	tMinusEqual:    "MINUS_EQUAL",
+	tStarEqual:     "STAR_EQUAL",
+	tSlashEqual:    "SLASH_EQUAL",
+	tModEqual:      "MOD_EQUAL",
+	tPlusPlus:      "PLUS_PLUS",
+	tMinusMinus:    "MINUS_MINUS",
+	tShiftLeft:     "SHIFT_LEFT",
+	tShiftRight:    "SHIFT_RIGHT",
+	tFloorDiv:      "FLOOR_DIV",
+	tStarStar:      "STAR_STAR",
+	tIdentifier:    "IDENTIFIER",
+	tString:        "STRING",
+	tInterpolation: "INTERPOLATION",
+	tNumber:        "NUMBER",
+	tAnd:           "AND",
+	tClass:         "CLASS",
+	tElse:          "ELSE",
+	tFalse:         "FALSE",
+	tFun:           "FUN",
+	tFor:           "FOR",
+	tIf:            "IF",
+	tNil:           "NIL",
+	tOr:            "OR",
+	tPrint:         "PRINT",
+	tReturn:        "RETURN",
+	tSuper:         "SUPER",
+	tThis:          "THIS",
+	tTrue:          "TRUE",
+	tVar:           "VAR",
+	tWhile:         "WHILE",
+	tEof:           "EOF",
 }
 
 var keywords = map[string]TokenType{
@@ -129,14 +160,51 @@ var keywords = map[string]TokenType{
 	"while":  tWhile,
 }
 
-var binaryTokens = []TokenType{tPlus, tStar, tMod, tXor, tSlash, tGreater, tLess, tEqual, tLessEqual, tGreaterEqual, tBangEqual, tEqualEqual, tAnd, tOr}
+var binaryTokens = []TokenType{tPlus, tStar, tMod, tXor, tBitAnd, tBitOr, tShiftLeft, tShiftRight, tFloorDiv, tStarStar, tSlash, tGreater, tLess, tEqual, tLessEqual, tGreaterEqual, tBangEqual, tEqualEqual, tAnd, tOr}
+
+// a += b is same as a = a + b, maps the assignment operator to the binary operator it applies
+var compoundAssignTokens = map[TokenType]TokenType{
+	tPlusEqual:  tPlus,
+	tMinusEqual: tMinus,
+	tStarEqual:  tStar,
+	tSlashEqual: tSlash,
+	tModEqual:   tMod,
+	tPlusPlus:   tPlus,
+	tMinusMinus: tMinus,
+}
 
 type token struct {
 	tokenType TokenType
 	lexeme    string
 	literal   interface{} // present for number and string
-	line      int
-	column    int
+	offset    int         // index of the first byte of the lexeme in the source
+	startLine int         // where the lexeme starts, only differs from line for multiline strings
+	startCol  int
+	line      int     // where the lexeme ends
+	column    int     // just after the lexeme
+	trivia    *trivia // comments and blank lines before the token, nil if there are none
+}
+
+/*
+what's between tokens but doesn't affect the program. It's a pointer, so tokens
+stay comparable to be used as map keys.
+*/
+type trivia struct {
+	comments        []comment
+	blankLineBefore bool // between the last comment(or the previous token) and the token
+}
+
+// a "//" comment till the end of the line
+type comment struct {
+	text            string // including the "//"
+	line            int
+	ownLine         bool // false if it's after a token on the same line
+	blankLineBefore bool
+}
+
+// column of the first character of the lexeme, 1 for the zero token
+func (t token) startColumn() int {
+	return max(t.startCol, 1)
 }
 
 func (t token) String() string {
@@ -166,11 +234,14 @@ func getTokenLiteralStr(literal interface{}) string {
 	}
 }
 
-func makeEOFToken(line, column int) token {
+func makeEOFToken(offset, line, column int) token {
 	return token{
 		tokenType: tEof,
 		lexeme:    "",
 		literal:   nil,
+		offset:    offset,
+		startLine: line,
+		startCol:  column,
 		line:      line,
 		column:    column,
 	}
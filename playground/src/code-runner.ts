@@ -4,6 +4,7 @@ export interface OutputLogger {
 	clear: () => void;
 	log: (arg: string) => void;
 	error: (arg: string) => void;
+	warning: (arg: string) => void;
 }
 
 let worker: Worker | null = null;
@@ -51,6 +52,9 @@ export async function runCode(
 					outputLogger.error(msgText);
 					break;
 				}
+				case "warning":
+					outputLogger.warning(data);
+					break;
 				case "fatal":
 					outputLogger.error(data);
 					reject(new Error(data));
@@ -20,7 +20,9 @@ import { codeStorage, debounce, defaultCode, jsLox } from "./utils";
 const isRunning = signal(false);
 const isAutoRunEnabled = signal(false);
 const editorView = signal<EditorView | null>(null);
-const outputLines = signal<{ text: string; isError: boolean }[]>([]);
+const outputLines = signal<
+	{ text: string; isError: boolean; isWarning?: boolean }[]
+>([]);
 
 const reErrorLine = /\[line (\d+)(:\d+)?\] (Error.+)/;
 
@@ -72,6 +74,12 @@ const outputLogger: OutputLogger = {
 	error: (errMsg: string) => {
 		outputLines.value = [...outputLines.value, { text: errMsg, isError: true }];
 	},
+	warning: (msg: string) => {
+		outputLines.value = [
+			...outputLines.value,
+			{ text: msg, isError: false, isWarning: true },
+		];
+	},
 };
 
 async function runCodeWithStateStuff(code: string, skipSave = false) {
@@ -242,7 +250,9 @@ function Output() {
 					class={
 						line.isError
 							? "text-red-700 whitespace-pre-wrap"
-							: "whitespace-pre-wrap"
+							: line.isWarning
+								? "text-yellow-700 whitespace-pre-wrap"
+								: "whitespace-pre-wrap"
 					}
 				>
 					<OutputLine text={line.text} />
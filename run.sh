@@ -3,6 +3,6 @@
 set -e # Exit early if any commands fail
 (
   cd "$(dirname "$0")" # Ensure compile steps are run within the repository directory
-  go build -o ./build/golox ./cmd/cli/main.go
+  go build -o ./build/golox ./cmd/cli
 )
 exec ./build/golox "$@"
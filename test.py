@@ -77,7 +77,7 @@ GO_SUITE_NAMES: list[str] = []
 
 
 def populate_go_tests():
-    def add_to_go_suite(test_name, tests_meta):
+    def add_to_go_suite(test_name, tests_meta, flags=[]):
         command = "run"
         if test_name == "chap04_scanning":
             command = "tokenize"
@@ -85,7 +85,7 @@ def populate_go_tests():
             command = "parse"
         elif test_name == "chap07_evaluating":
             command = "evaluate"
-        args = ["./build/golox", command]
+        args = ["./build/golox", command, *flags]
         TEST_SUITES[test_name] = TestSuite(test_name, "go", args, tests_meta)
         GO_SUITE_NAMES.append(test_name)
 
@@ -173,9 +173,20 @@ def populate_go_tests():
             **noLanguageLimits,
             # extensions
             "test/extensions/array_init.lox": "pass",
+            # need the --seed flag, run in golox_seeded
+            "test/seeded": "skip",
         },
     )
 
+    add_to_go_suite(
+        "golox_seeded",
+        {
+            "test": "skip",
+            "test/seeded": "pass",
+        },
+        flags=["--seed=42"],
+    )
+
     add_to_go_suite(
         "chap04_scanning",
         {
@@ -1042,7 +1053,7 @@ def run_suite(name, filter_path: str):
 
 
 def make_go_build():
-    command = "go build -o ./build/golox ./cmd/cli/main.go"
+    command = "go build -o ./build/golox ./cmd/cli"
     proc = Popen(command, shell=True)
     proc.wait()
     if proc.returncode != 0: